@@ -0,0 +1,317 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/cmdline"
+	"snippetbox.xmxxmx.us/internal/models"
+	"snippetbox.xmxxmx.us/internal/validator"
+)
+
+// user looks up the user with the given email address, which is required.
+func (a *admin) user(email string) (models.User, error) {
+	if email == "" {
+		return models.User{}, cmdline.UsageError("the -email flag is required")
+	}
+
+	user, err := a.users.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			return models.User{}, fmt.Errorf("no user with email %s", email)
+		}
+		return models.User{}, err
+	}
+
+	return user, nil
+}
+
+// readPassword reads a new password from the first line of stdin. Reading it
+// from stdin, rather than a flag, keeps it out of the shell history and the
+// process list. It must pass the same check as the signup form.
+func (a *admin) readPassword() (string, error) {
+	line, err := a.stdin.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if !validator.MinChars(password, 8) {
+		return "", errors.New("the password on standard input must be at least 8 characters long")
+	}
+
+	return password, nil
+}
+
+// createUser 创建新用户，密码从标准输入读取
+func (a *admin) createUser(args []string) error {
+	fs := cmdline.FlagSet("admin create-user", a.env.stderr)
+	name := fs.String("name", "", "Name of the user (required)")
+	email := fs.String("email", "", "Email address of the user (required)")
+
+	err := cmdline.Parse(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(*name) == "" {
+		return cmdline.UsageError("the -name flag is required")
+	}
+	if !validator.Matches(*email, validator.EmailRX) {
+		return cmdline.UsageError("the -email flag must be a valid email address")
+	}
+
+	password, err := a.readPassword()
+	if err != nil {
+		return err
+	}
+
+	err = a.users.Insert(*name, *email, password)
+	if err != nil {
+		if errors.Is(err, models.ErrDuplicateEmail) {
+			return fmt.Errorf("email %s is already in use", *email)
+		}
+		return err
+	}
+
+	_, err = fmt.Fprintf(a.env.stdout, "Created user %s\n", *email)
+	return err
+}
+
+// resetPassword 重置用户的密码，新密码从标准输入读取
+func (a *admin) resetPassword(args []string) error {
+	fs := cmdline.FlagSet("admin reset-password", a.env.stderr)
+	email := fs.String("email", "", "Email address of the user (required)")
+
+	err := cmdline.Parse(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.user(*email)
+	if err != nil {
+		return err
+	}
+
+	password, err := a.readPassword()
+	if err != nil {
+		return err
+	}
+
+	err = a.users.SetPassword(user.ID, password)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(a.env.stdout, "Reset the password of %s\n", user.Email)
+	return err
+}
+
+// disableUser 禁用用户，被禁用的用户不能登录，也不能使用 API 令牌
+func (a *admin) disableUser(args []string) error {
+	return a.setDisabled("disable-user", args, true)
+}
+
+// enableUser 重新启用被禁用的用户
+func (a *admin) enableUser(args []string) error {
+	return a.setDisabled("enable-user", args, false)
+}
+
+// setDisabled implements the disable-user and enable-user commands.
+func (a *admin) setDisabled(name string, args []string, disabled bool) error {
+	fs := cmdline.FlagSet("admin "+name, a.env.stderr)
+	email := fs.String("email", "", "Email address of the user (required)")
+
+	err := cmdline.Parse(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.user(*email)
+	if err != nil {
+		return err
+	}
+
+	err = a.users.SetDisabled(user.ID, disabled)
+	if err != nil {
+		return err
+	}
+
+	action := "Enabled"
+	if disabled {
+		action = "Disabled"
+	}
+
+	_, err = fmt.Fprintf(a.env.stdout, "%s %s\n", action, user.Email)
+	return err
+}
+
+// printSnippets writes a table of snippets to stdout.
+func (a *admin) printSnippets(snippets []models.Snippet) error {
+	tw := tabwriter.NewWriter(a.env.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SLUG\tCREATED\tEXPIRES\tVISIBILITY\tTITLE")
+
+	for _, s := range snippets {
+		expires := "never"
+		switch {
+		case s.Burned:
+			expires = "burned"
+		case s.Expired():
+			expires = "expired"
+		case !s.Expires.IsZero():
+			expires = s.Expires.UTC().Format(time.DateTime)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Slug, s.Created.UTC().Format(time.DateTime), expires, s.Visibility, s.Title)
+	}
+
+	return tw.Flush()
+}
+
+// listSnippets 列出用户的全部代码片段，包括已经过期和已经销毁的
+func (a *admin) listSnippets(args []string) error {
+	fs := cmdline.FlagSet("admin list-snippets", a.env.stderr)
+	email := fs.String("email", "", "Email address of the user (required)")
+	sort := fs.String("sort", "-created", "Sort order: -created, created, -expires or expires")
+
+	err := cmdline.Parse(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.user(*email)
+	if err != nil {
+		return err
+	}
+
+	snippets, err := a.snippets.ForUser(user.ID, strings.TrimSpace(*sort))
+	if err != nil {
+		return err
+	}
+
+	return a.printSnippets(snippets)
+}
+
+// deleteSnippets 删除用户的一个代码片段，或者不指定 -slug 时删除全部代码片段。
+// 没有 -yes 时只列出将被删除的片段
+func (a *admin) deleteSnippets(args []string) error {
+	fs := cmdline.FlagSet("admin delete-snippets", a.env.stderr)
+	email := fs.String("email", "", "Email address of the user (required)")
+	slug := fs.String("slug", "", "Slug of the snippet to delete, instead of all of them")
+	yes := fs.Bool("yes", false, "Delete the snippets, rather than just listing them")
+
+	err := cmdline.Parse(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.user(*email)
+	if err != nil {
+		return err
+	}
+
+	snippets, err := a.snippets.ForUser(user.ID, "")
+	if err != nil {
+		return err
+	}
+
+	if *slug != "" {
+		var matched []models.Snippet
+		for _, s := range snippets {
+			if s.Slug == *slug {
+				matched = append(matched, s)
+			}
+		}
+		if len(matched) == 0 {
+			return fmt.Errorf("%s has no snippet %s", user.Email, *slug)
+		}
+		snippets = matched
+	}
+
+	// Without -yes, show what would be deleted so that it can be checked
+	// before running the command again.
+	if !*yes {
+		err = a.printSnippets(snippets)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(a.env.stdout, "Run again with -yes to delete these %d snippets\n", len(snippets))
+		return err
+	}
+
+	for _, s := range snippets {
+		err = a.snippets.Delete(s.ID)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(a.env.stdout, "Deleted %d snippets\n", len(snippets))
+	return err
+}
+
+// purgeExpired 删除过期超过 -grace 的代码片段，与 web 应用的清理任务相同，
+// 但会一直删除到没有剩余为止
+func (a *admin) purgeExpired(args []string) error {
+	fs := cmdline.FlagSet("admin purge-expired", a.env.stderr)
+	grace := fs.Duration("grace", 24*time.Hour, "How long to keep snippets after they expire")
+	batch := fs.Int("batch", 500, "Maximum number of expired snippets to delete at once")
+
+	err := cmdline.Parse(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	if *grace < 0 {
+		return cmdline.UsageError("the -grace flag must not be negative")
+	}
+	if *batch < 1 {
+		return cmdline.UsageError("the -batch flag must be at least 1")
+	}
+
+	before := a.now().Add(-*grace)
+	total := 0
+
+	for {
+		n, err := a.snippets.DeleteExpired(before, *batch)
+		if err != nil {
+			return err
+		}
+		total += n
+
+		if n < *batch {
+			break
+		}
+	}
+
+	_, err = fmt.Fprintf(a.env.stdout, "Purged %d expired snippets\n", total)
+	return err
+}
+
+// printStats 输出数据库中各类记录的数量
+func (a *admin) printStats(args []string) error {
+	err := cmdline.Parse(cmdline.FlagSet("admin stats", a.env.stderr), args, 0)
+	if err != nil {
+		return err
+	}
+
+	s, err := a.stats.Get()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(a.env.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Users:\t%d\n", s.Users)
+	fmt.Fprintf(tw, "Disabled users:\t%d\n", s.DisabledUsers)
+	fmt.Fprintf(tw, "Snippets:\t%d\n", s.Snippets)
+	fmt.Fprintf(tw, "Active snippets:\t%d\n", s.ActiveSnippets)
+	fmt.Fprintf(tw, "Expired snippets:\t%d\n", s.ExpiredSnippets)
+	fmt.Fprintf(tw, "Burned snippets:\t%d\n", s.BurnedSnippets)
+	fmt.Fprintf(tw, "Revisions:\t%d\n", s.Revisions)
+	fmt.Fprintf(tw, "Tags:\t%d\n", s.Tags)
+	fmt.Fprintf(tw, "API tokens:\t%d\n", s.APITokens)
+
+	return tw.Flush()
+}
@@ -0,0 +1,143 @@
+// admin 是 snippetbox 的维护工具，直接使用 internal/models 访问数据库，用于创建
+// 用户、重置密码、禁用账号、管理代码片段和查看统计信息，例如
+// admin -dsn "..." reset-password -email alice@example.com < password.txt
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/cmdline"
+	"snippetbox.xmxxmx.us/internal/config"
+	"snippetbox.xmxxmx.us/internal/models"
+)
+
+const usage = `Usage: admin [-config file] [-db-backend b] [-dsn dsn | -dsn-file file] <command> [arguments]
+
+Commands:
+  create-user -name n -email e
+                   create a user, reading their password from standard input
+  reset-password -email e
+                   set a user's password, reading it from standard input
+  disable-user -email e
+                   stop a user logging in or using their API tokens
+  enable-user -email e
+                   let a disabled user log in again
+  list-snippets -email e [-sort s]
+                   list all of a user's snippets, including expired ones
+  delete-snippets -email e [-slug s] [-yes]
+                   delete one or all of a user's snippets
+  purge-expired [-grace d] [-batch n]
+                   delete snippets which expired more than -grace ago
+  stats            print the number of users, snippets and so on
+
+Flags:
+`
+
+// env 保存命令运行时使用的输入输出和环境变量，测试时可以替换
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+}
+
+// commands maps each subcommand's name to the method which runs it. An error
+// from the method is printed to stderr and decides the exit status.
+var commands = map[string]func(a *admin, args []string) error{
+	"create-user":     (*admin).createUser,
+	"reset-password":  (*admin).resetPassword,
+	"disable-user":    (*admin).disableUser,
+	"enable-user":     (*admin).enableUser,
+	"list-snippets":   (*admin).listSnippets,
+	"delete-snippets": (*admin).deleteSnippets,
+	"purge-expired":   (*admin).purgeExpired,
+	"stats":           (*admin).printStats,
+}
+
+// admin holds the models and the input and output shared by the subcommands.
+type admin struct {
+	env      env
+	stdin    *bufio.Reader
+	users    *models.UserModel
+	snippets *models.SnippetModel
+	stats    *models.StatsModel
+	now      func() time.Time
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], env{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		getenv: os.Getenv,
+	}))
+}
+
+// run runs the admin tool with the given command line arguments (not
+// including the program name) and returns the exit status.
+func run(args []string, e env) int {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprint(e.stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	// The database settings are loaded in the same way as the web
+	// application's, from the same flags, environment variables and config
+	// file, so that both can be pointed at a database in the same way.
+	cfg, err := config.Load(fs, args, e.getenv, "db-backend", "dsn", "dsn-file")
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(e.stderr, "admin: %v\n", err)
+		}
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(e.stderr, "admin: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	err = cfg.Validate("db-backend", "dsn")
+	if err != nil {
+		fmt.Fprintf(e.stderr, "admin: %v\n", err)
+		return 2
+	}
+
+	db, err := models.Open(models.Backend(cfg.DBBackend), cfg.DSN)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "admin: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	a := &admin{
+		env:      e,
+		stdin:    bufio.NewReader(e.stdin),
+		users:    &models.UserModel{DB: db},
+		snippets: &models.SnippetModel{DB: db},
+		stats:    &models.StatsModel{DB: db},
+		now:      time.Now,
+	}
+
+	err = cmd(a, fs.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(e.stderr, "admin %s: %v\n", fs.Arg(0), err)
+	}
+
+	return cmdline.ExitStatus(err)
+}
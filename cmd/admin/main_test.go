@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+	"snippetbox.xmxxmx.us/internal/models"
+)
+
+const testDSN = "test_web:pass@/test_snippetbox?parseTime=true&multiStatements=true"
+
+// newTestDB sets up the test database with the same scripts as the model
+// tests, and tears it down again when the test has finished.
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("mysql", testDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := os.ReadFile("../../internal/models/testdata/setup.sql")
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+	_, err = db.Exec(string(script))
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		defer db.Close()
+
+		script, err := os.ReadFile("../../internal/models/testdata/teardown.sql")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.Exec(string(script))
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	return db
+}
+
+// runAdmin runs the admin tool against the test database, with stdin as its
+// standard input, and returns its exit status and output.
+func runAdmin(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	status := run(append([]string{"-dsn", testDSN}, args...), env{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(string) string { return "" },
+	})
+
+	return status, stdout.String(), stderr.String()
+}
+
+func TestAdmin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("admin: skipping integration test")
+	}
+
+	db := newTestDB(t)
+	users := &models.UserModel{DB: db}
+
+	t.Run("Create user", func(t *testing.T) {
+		status, stdout, _ := runAdmin(t, "s3cret pa$$word\n", "create-user", "-name", "Bob", "-email", "bob@example.com")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "Created user bob@example.com")
+
+		id, err := users.Authenticate("bob@example.com", "s3cret pa$$word")
+		assert.NilError(t, err)
+		assert.Equal(t, id, 2)
+	})
+
+	t.Run("Create duplicate user", func(t *testing.T) {
+		status, _, stderr := runAdmin(t, "s3cret pa$$word\n", "create-user", "-name", "Bob", "-email", "bob@example.com")
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "email bob@example.com is already in use")
+	})
+
+	t.Run("Short password", func(t *testing.T) {
+		status, _, stderr := runAdmin(t, "short\n", "create-user", "-name", "Carol", "-email", "carol@example.com")
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "at least 8 characters long")
+	})
+
+	t.Run("Reset password", func(t *testing.T) {
+		status, stdout, _ := runAdmin(t, "n3w pa$$word", "reset-password", "-email", "bob@example.com")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "Reset the password of bob@example.com")
+
+		_, err := users.Authenticate("bob@example.com", "n3w pa$$word")
+		assert.NilError(t, err)
+	})
+
+	t.Run("Disable and enable user", func(t *testing.T) {
+		status, stdout, _ := runAdmin(t, "", "disable-user", "-email", "bob@example.com")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "Disabled bob@example.com")
+
+		_, err := users.Authenticate("bob@example.com", "n3w pa$$word")
+		assert.Equal(t, err, models.ErrAccountDisabled)
+
+		status, stdout, _ = runAdmin(t, "", "enable-user", "-email", "bob@example.com")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "Enabled bob@example.com")
+
+		_, err = users.Authenticate("bob@example.com", "n3w pa$$word")
+		assert.NilError(t, err)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		status, _, stderr := runAdmin(t, "", "disable-user", "-email", "nobody@example.com")
+		assert.Equal(t, status, 1)
+		assert.StringContains(t, stderr, "no user with email nobody@example.com")
+	})
+
+	t.Run("List snippets", func(t *testing.T) {
+		status, stdout, _ := runAdmin(t, "", "list-snippets", "-email", "alice@example.com")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "q7Yx2LpK0aZ")
+		assert.StringContains(t, stdout, "An old silent pond")
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		status, stdout, _ := runAdmin(t, "", "stats")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "Users:             2")
+		assert.StringContains(t, stdout, "Snippets:          1")
+	})
+
+	t.Run("Delete snippets without -yes", func(t *testing.T) {
+		status, stdout, _ := runAdmin(t, "", "delete-snippets", "-email", "alice@example.com")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "Run again with -yes to delete these 1 snippets")
+
+		_, err := (&models.SnippetModel{DB: db}).GetBySlug("q7Yx2LpK0aZ")
+		assert.NilError(t, err)
+	})
+
+	t.Run("Delete snippets", func(t *testing.T) {
+		status, stdout, _ := runAdmin(t, "", "delete-snippets", "-email", "alice@example.com", "-slug", "q7Yx2LpK0aZ", "-yes")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "Deleted 1 snippets")
+
+		_, err := (&models.SnippetModel{DB: db}).GetBySlug("q7Yx2LpK0aZ")
+		assert.Equal(t, err, models.ErrNoRecord)
+	})
+
+	t.Run("Purge expired", func(t *testing.T) {
+		status, stdout, _ := runAdmin(t, "", "purge-expired", "-grace", "0s")
+		assert.Equal(t, status, 0)
+		assert.StringContains(t, stdout, "Purged 0 expired snippets")
+	})
+
+	t.Run("Unknown command", func(t *testing.T) {
+		status, _, stderr := runAdmin(t, "", "frobnicate")
+		assert.Equal(t, status, 2)
+		assert.StringContains(t, stderr, `unknown command "frobnicate"`)
+	})
+
+	t.Run("Missing DSN", func(t *testing.T) {
+		var stderr bytes.Buffer
+		status := run([]string{"stats"}, env{
+			stdin:  strings.NewReader(""),
+			stdout: io.Discard,
+			stderr: &stderr,
+			getenv: func(string) string { return "" },
+		})
+		assert.Equal(t, status, 2)
+		assert.StringContains(t, stderr.String(), "dsn must be set")
+	})
+
+	t.Run("Missing email", func(t *testing.T) {
+		status, _, stderr := runAdmin(t, "", "list-snippets")
+		assert.Equal(t, status, 2)
+		assert.StringContains(t, stderr, "the -email flag is required")
+	})
+}
+
+func TestAdminSQLite(t *testing.T) {
+	// The SQLite database is created by the first command which opens it, so
+	// this test needs no database server.
+	dsn := filepath.Join(t.TempDir(), "snippetbox.db")
+
+	admin := func(stdin string, args ...string) (int, string, string) {
+		var stdout, stderr bytes.Buffer
+		status := run(append([]string{"-db-backend", "sqlite", "-dsn", dsn}, args...), env{
+			stdin:  strings.NewReader(stdin),
+			stdout: &stdout,
+			stderr: &stderr,
+			getenv: func(string) string { return "" },
+		})
+		return status, stdout.String(), stderr.String()
+	}
+
+	status, stdout, _ := admin("s3cret pa$$word\n", "create-user", "-name", "Bob", "-email", "bob@example.com")
+	assert.Equal(t, status, 0)
+	assert.StringContains(t, stdout, "Created user bob@example.com")
+
+	status, _, stderr := admin("s3cret pa$$word\n", "create-user", "-name", "Bob", "-email", "bob@example.com")
+	assert.Equal(t, status, 1)
+	assert.StringContains(t, stderr, "email bob@example.com is already in use")
+
+	status, stdout, _ = admin("", "stats")
+	assert.Equal(t, status, 0)
+	assert.StringContains(t, stdout, "Users:             1")
+}
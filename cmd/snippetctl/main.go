@@ -0,0 +1,17 @@
+// snippetctl 是 snippetbox 的命令行客户端，使用配置文件中的 API 令牌访问服务器的
+// JSON API，例如 snippetctl create -t "Backup script" < backup.sh
+package main
+
+import (
+	"os"
+
+	"snippetbox.xmxxmx.us/internal/snippetctl"
+)
+
+func main() {
+	os.Exit(snippetctl.Run(os.Args[1:], snippetctl.Env{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}))
+}
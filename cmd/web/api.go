@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/models"
+	"snippetbox.xmxxmx.us/internal/validator"
+)
+
+// maxAPIBodyBytes limits the size of a JSON request body.
+const maxAPIBodyBytes = 1 << 20
+
+// apiSnippet 是代码片段在 JSON API 中的表示。Expires 为 nil 表示永不过期，
+// 访问密码和内部使用的数字 ID 不会出现在响应中。
+type apiSnippet struct {
+	Slug             string     `json:"slug"`
+	Title            string     `json:"title"`
+	Content          string     `json:"content"`
+	Created          time.Time  `json:"created"`
+	Updated          time.Time  `json:"updated"`
+	Expires          *time.Time `json:"expires"`
+	Author           string     `json:"author"`
+	Tags             []string   `json:"tags"`
+	Language         string     `json:"language"`
+	Visibility       string     `json:"visibility"`
+	BurnAfterReading bool       `json:"burn_after_reading"`
+	Protected        bool       `json:"protected"`
+	URL              string     `json:"url"`
+}
+
+// newAPISnippet 把 models.Snippet 转换为 API 响应中的 apiSnippet。当前用户还没有
+// 解锁的密码保护片段不包含内容。
+func (app *application) newAPISnippet(r *http.Request, s models.Snippet) apiSnippet {
+	a := apiSnippet{
+		Slug:             s.Slug,
+		Title:            s.Title,
+		Content:          s.Content,
+		Created:          s.Created,
+		Updated:          s.Updated,
+		Author:           s.UserName,
+		Tags:             s.Tags,
+		Language:         s.Language,
+		Visibility:       s.Visibility,
+		BurnAfterReading: s.BurnAfterReading,
+		Protected:        s.Protected,
+		URL:              "/snippet/view/" + s.Slug,
+	}
+	if !s.Expires.IsZero() {
+		a.Expires = &s.Expires
+	}
+	if a.Tags == nil {
+		a.Tags = []string{}
+	}
+	if app.snippetLocked(r, s) {
+		a.Content = ""
+	}
+
+	return a
+}
+
+// apiSnippetCreateRequest holds the JSON body of a create snippet request.
+// Expires is an RFC 3339 time, and a missing or null value means the snippet
+// never expires.
+type apiSnippetCreateRequest struct {
+	Title            string     `json:"title"`
+	Content          string     `json:"content"`
+	Expires          *time.Time `json:"expires"`
+	Tags             []string   `json:"tags"`
+	Language         string     `json:"language"`
+	Visibility       string     `json:"visibility"`
+	BurnAfterReading bool       `json:"burn_after_reading"`
+	Password         string     `json:"password"`
+}
+
+// apiSnippetUpdateRequest holds the JSON body of an update snippet request.
+// Like the edit form, only the title and content can be changed.
+type apiSnippetUpdateRequest struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// apiErrorBody is the "error" object of every API error response. Fields
+// holds the validation error for each invalid field of the request, if any.
+type apiErrorBody struct {
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// writeJSON encodes data as JSON and writes it to the response with the
+// given status code.
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data any) {
+	js, err := json.Marshal(data)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+	w.Write([]byte("\n"))
+}
+
+// readJSON decodes a JSON request body into dst. Unknown fields, trailing
+// data and bodies over maxAPIBodyBytes are rejected, and the error returned
+// describes the problem in a way that can be shown to the client.
+func readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxAPIBodyBytes)
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		var maxBytesError *http.MaxBytesError
+		var parseError *time.ParseError
+
+		switch {
+		case errors.As(err, &syntaxError):
+			return fmt.Errorf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return errors.New("body contains badly-formed JSON")
+		case errors.As(err, &typeError):
+			if typeError.Field != "" {
+				return fmt.Errorf("body contains the wrong JSON type for field %q", typeError.Field)
+			}
+			return fmt.Errorf("body contains the wrong JSON type (at character %d)", typeError.Offset)
+		case errors.Is(err, io.EOF):
+			return errors.New("body must not be empty")
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			return fmt.Errorf("body contains unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+		case errors.As(err, &maxBytesError):
+			return fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		case errors.As(err, &parseError):
+			return errors.New("body contains a time which isn't in RFC 3339 format")
+		default:
+			return err
+		}
+	}
+
+	if dec.More() {
+		return errors.New("body must only contain a single JSON value")
+	}
+
+	return nil
+}
+
+// apiError 以 JSON 格式返回错误信息，而不是 http.Error() 的纯文本
+func (app *application) apiError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	app.writeJSON(w, r, status, map[string]any{"error": apiErrorBody{Message: message}})
+}
+
+// apiServerError 记录服务器错误并以 JSON 格式返回 500 状态码
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+
+	// Don't go through writeJSON() here, as it calls apiServerError() itself
+	// if the encoding fails.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(map[string]any{"error": apiErrorBody{Message: "the server encountered a problem and could not process the request"}})
+}
+
+// apiValidationError 返回 422 状态码以及每个无效字段的错误信息
+func (app *application) apiValidationError(w http.ResponseWriter, r *http.Request, v validator.Validator) {
+	message := "the request contains invalid fields"
+	if len(v.NonFieldErrors) > 0 {
+		message = v.NonFieldErrors[0]
+	}
+
+	app.writeJSON(w, r, http.StatusUnprocessableEntity, map[string]any{"error": apiErrorBody{Message: message, Fields: v.FieldErrors}})
+}
+
+// apiNotFound 处理 /api/v1/ 下所有不存在的路径
+func (app *application) apiNotFound(w http.ResponseWriter, r *http.Request) {
+	app.apiError(w, r, http.StatusNotFound, "the requested resource could not be found")
+}
+
+// apiReadableSnippet 与 readableSnippet 相同，但以 JSON 格式返回错误。受密码保护的
+// 片段如果没有在同一个会话中解锁，会返回 403 而不是跳转到解锁页面。
+func (app *application) apiReadableSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	snippet, err := app.snippets.GetBySlug(r.PathValue("slug"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w, r)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	switch {
+	case !snippet.VisibleTo(userID):
+		app.apiNotFound(w, r)
+	case snippet.Burned:
+		app.apiError(w, r, http.StatusGone, "the snippet has been viewed and destroyed")
+	case snippet.BurnAfterReading && snippet.UserID != userID:
+		app.apiNotFound(w, r)
+	case app.snippetLocked(r, snippet):
+		app.apiError(w, r, http.StatusForbidden, "the snippet is password protected")
+	default:
+		return snippet, true
+	}
+
+	return models.Snippet{}, false
+}
+
+// apiOwnedSnippet 读取 URL 路径中 slug 对应的代码片段，并检查它是否属于当前用户
+func (app *application) apiOwnedSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	// Expired snippets are still looked up, so that their owner can update
+	// or delete them like in the web interface.
+	snippet, err := app.snippets.GetBySlugForOwner(r.PathValue("slug"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.apiNotFound(w, r)
+		} else {
+			app.apiServerError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	// Other people's private snippets are reported as not found, the same as
+	// when reading them, so that the API doesn't reveal that they exist.
+	switch {
+	case !snippet.VisibleTo(userID):
+		app.apiNotFound(w, r)
+	case snippet.UserID != userID:
+		app.apiError(w, r, http.StatusForbidden, "you don't have permission to change this snippet")
+	default:
+		return snippet, true
+	}
+
+	return models.Snippet{}, false
+}
+
+// apiSnippetList 分页返回公开的代码片段，参数与首页的查询字符串相同
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+	var form snippetListForm
+
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "the query string contains an invalid value")
+		return
+	}
+
+	from, fromErr := parseDate(form.From)
+	to, toErr := parseDate(form.To)
+	tag := r.URL.Query().Get("tag")
+
+	form.CheckField(form.Before >= 0 && form.After >= 0, "cursor", "Invalid page cursor")
+	form.CheckField(form.Size >= 0 && form.Size <= models.MaxPageSize, "size", fmt.Sprintf("This field must be between 1 and %d", models.MaxPageSize))
+	form.CheckField(fromErr == nil, "from", "This field must be a valid date")
+	form.CheckField(toErr == nil, "to", "This field must be a valid date")
+	form.CheckField(from.IsZero() || to.IsZero() || !to.Before(from), "to", "This field must not be before the from date")
+	form.CheckField(tag == "" || validator.Matches(tag, validator.TagRX), "tag", "This field must be a valid tag")
+
+	if !form.Valid() {
+		app.apiValidationError(w, r, form.Validator)
+		return
+	}
+
+	filter := models.SnippetFilter{
+		Before:      form.Before,
+		After:       form.After,
+		PageSize:    form.Size,
+		CreatedFrom: from,
+		Tag:         tag,
+	}
+	if !to.IsZero() {
+		filter.CreatedTo = to.AddDate(0, 0, 1)
+	}
+
+	snippets, page, err := app.snippets.List(filter)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	list := make([]apiSnippet, len(snippets))
+	for i, s := range snippets {
+		list[i] = app.newAPISnippet(r, s)
+	}
+
+	// The cursors are the values to pass as the before and after parameters
+	// to fetch the next and previous pages, and zero when there isn't one.
+	app.writeJSON(w, r, http.StatusOK, map[string]any{
+		"snippets":    list,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+	})
+}
+
+// apiSearch 全文搜索公开的代码片段，游标是页码，参数与搜索页面的查询字符串相同
+func (app *application) apiSearch(w http.ResponseWriter, r *http.Request) {
+	var form searchForm
+
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil {
+		app.apiError(w, r, http.StatusBadRequest, "the query string contains an invalid value")
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Query), "q", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Query, 100), "q", "This field cannot be more than 100 characters long")
+	form.CheckField(form.Page >= 0, "page", "Invalid page number")
+
+	if !form.Valid() {
+		app.apiValidationError(w, r, form.Validator)
+		return
+	}
+
+	snippets, page, err := app.snippets.Search(form.Query, form.Page, models.DefaultPageSize)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	list := make([]apiSnippet, len(snippets))
+	for i, s := range snippets {
+		list[i] = app.newAPISnippet(r, s)
+	}
+
+	app.writeJSON(w, r, http.StatusOK, map[string]any{
+		"snippets":    list,
+		"next_cursor": page.NextCursor,
+		"prev_cursor": page.PrevCursor,
+	})
+}
+
+// apiSnippetGet 返回单个代码片段
+func (app *application) apiSnippetGet(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiReadableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeJSON(w, r, http.StatusOK, map[string]any{"snippet": app.newAPISnippet(r, snippet)})
+}
+
+// apiSnippetCreate 创建新的代码片段，成功时返回 201 状态码和新片段
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+	var input apiSnippetCreateRequest
+
+	err := readJSON(w, r, &input)
+	if err != nil {
+		app.apiError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// The checks are the same as for the create snippet form, except that the
+	// tags are already a list and the expiry is an exact time. Like the form's
+	// tags, they're lowercased and repeated ones are dropped.
+	var v validator.Validator
+
+	tags := normalizeTags(input.Tags)
+
+	v.CheckField(validator.NotBlank(input.Title), "title", "This field cannot be blank")
+	v.CheckField(validator.MaxChars(input.Title, 100), "title", "This field cannot be more than 100 characters long")
+	v.CheckField(validator.NotBlank(input.Content), "content", "This field cannot be blank")
+
+	var expires time.Time
+	if input.Expires != nil {
+		expires = input.Expires.UTC()
+		v.CheckField(expires.After(time.Now()), "expires", "This field must be in the future")
+		v.CheckField(!expires.After(time.Now().Add(maxExpiry)), "expires", "This field cannot be more than 10 years from now")
+	}
+
+	v.CheckField(validator.MaxItems(tags, maxTags), "tags", fmt.Sprintf("This field cannot contain more than %d tags", maxTags))
+	v.CheckField(validator.AllMatch(tags, validator.TagRX), "tags", "Tags can only contain letters, digits and the characters + # . _ - and be at most 30 characters long")
+	v.CheckField(validator.PermittedValue(input.Language, append(languageValues(), autoDetectLanguage)...), "language", "This field must be one of the listed languages")
+	v.CheckField(validator.PermittedValue(input.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must equal public, unlisted or private")
+	v.CheckField(input.Password == "" || validator.MinChars(input.Password, 8), "password", "This field must be at least 8 characters long")
+
+	if !v.Valid() {
+		app.apiValidationError(w, r, v)
+		return
+	}
+
+	language := input.Language
+	if language == autoDetectLanguage {
+		language = detectLanguage(input.Content)
+	}
+
+	_, slug, err := app.snippets.Insert(models.NewSnippet{
+		Title:      input.Title,
+		Content:    input.Content,
+		Expires:    expires,
+		UserID:     app.authenticatedUserID(r),
+		Tags:       tags,
+		Language:   language,
+		Visibility: input.Visibility,
+
+		BurnAfterReading: input.BurnAfterReading,
+		Password:         input.Password,
+	})
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	snippet, err := app.snippets.GetBySlug(slug)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/snippets/"+slug)
+	app.writeJSON(w, r, http.StatusCreated, map[string]any{"snippet": app.newAPISnippet(r, snippet)})
+}
+
+// apiSnippetUpdate 修改代码片段的标题和内容，并返回修改后的片段
+func (app *application) apiSnippetUpdate(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	if snippet.Burned {
+		app.apiError(w, r, http.StatusGone, "the snippet has been viewed and destroyed")
+		return
+	}
+
+	var input apiSnippetUpdateRequest
+
+	err := readJSON(w, r, &input)
+	if err != nil {
+		app.apiError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var v validator.Validator
+
+	v.CheckField(validator.NotBlank(input.Title), "title", "This field cannot be blank")
+	v.CheckField(validator.MaxChars(input.Title, 100), "title", "This field cannot be more than 100 characters long")
+	v.CheckField(validator.NotBlank(input.Content), "content", "This field cannot be blank")
+
+	if !v.Valid() {
+		app.apiValidationError(w, r, v)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, app.authenticatedUserID(r), input.Title, input.Content)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	snippet, err = app.snippets.GetForOwner(snippet.ID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	app.writeJSON(w, r, http.StatusOK, map[string]any{"snippet": app.newAPISnippet(r, snippet)})
+}
+
+// apiSnippetDelete 删除代码片段，成功时返回 204 状态码
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.apiOwnedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		app.apiServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,585 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+// apiErrorResponse holds a decoded API error response.
+type apiErrorResponse struct {
+	Error apiErrorBody `json:"error"`
+}
+
+// decodeJSON decodes a JSON response body into dst, failing the test if it
+// isn't valid JSON.
+func decodeJSON(t *testing.T, body string, dst any) {
+	err := json.Unmarshal([]byte(body), dst)
+	if err != nil {
+		t.Fatalf("invalid JSON response %q: %v", body, err)
+	}
+}
+
+// apiLogin logs the test server client in as the given user and returns the
+// headers needed to make cookie authenticated API requests which change
+// data, including a valid CSRF token.
+func apiLogin(t *testing.T, ts *testServer, userID string) http.Header {
+	ts.get(t, "/test/login/"+userID)
+
+	_, _, body := ts.get(t, "/snippet/view/q7Yx2LpK0aZ")
+
+	return http.Header{
+		"Content-Type":   {"application/json"},
+		"Sec-Fetch-Site": {"same-origin"},
+		"X-Csrf-Token":   {extractCSRFToken(t, body)},
+	}
+}
+
+func TestAPISnippetList(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		urlPath   string
+		wantCode  int
+		wantCount int
+		wantField string
+	}{
+		{
+			name:      "All snippets",
+			urlPath:   "/api/v1/snippets",
+			wantCode:  http.StatusOK,
+			wantCount: 2,
+		},
+		{
+			name:      "Tag with snippets",
+			urlPath:   "/api/v1/snippets?tag=haiku",
+			wantCode:  http.StatusOK,
+			wantCount: 1,
+		},
+		{
+			name:     "Tag without snippets",
+			urlPath:  "/api/v1/snippets?tag=go",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:      "Page size too large",
+			urlPath:   "/api/v1/snippets?size=1000",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantField: "size",
+		},
+		{
+			name:      "Invalid date",
+			urlPath:   "/api/v1/snippets?from=yesterday",
+			wantCode:  http.StatusUnprocessableEntity,
+			wantField: "from",
+		},
+		{
+			name:     "Invalid cursor",
+			urlPath:  "/api/v1/snippets?before=abc",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Content-Type"), "application/json")
+
+			if code != http.StatusOK {
+				var res apiErrorResponse
+				decodeJSON(t, body, &res)
+				assert.Equal(t, res.Error.Message != "", true)
+				if tt.wantField != "" {
+					assert.Equal(t, res.Error.Fields[tt.wantField] != "", true)
+				}
+				return
+			}
+
+			var res struct {
+				Snippets []apiSnippet `json:"snippets"`
+			}
+			decodeJSON(t, body, &res)
+			assert.Equal(t, len(res.Snippets), tt.wantCount)
+		})
+	}
+}
+
+func TestAPISnippetListProtected(t *testing.T) {
+	app := newTestApplication(t)
+
+	const content = "The guest network password is hunter2"
+
+	// listedContent returns the content of the public protected snippet in
+	// the API's list of snippets.
+	listedContent := func(t *testing.T, ts *testServer) string {
+		code, _, body := ts.get(t, "/api/v1/snippets")
+		assert.Equal(t, code, http.StatusOK)
+
+		var res struct {
+			Snippets []apiSnippet `json:"snippets"`
+		}
+		decodeJSON(t, body, &res)
+
+		for _, s := range res.Snippets {
+			if s.Slug == "Wf8kN3pXs6J" {
+				assert.Equal(t, s.Protected, true)
+				return s.Content
+			}
+		}
+
+		t.Fatal("protected snippet not listed")
+		return ""
+	}
+
+	tests := []struct {
+		name        string
+		userID      int
+		wantContent string
+	}{
+		{
+			name:        "Anonymous",
+			wantContent: "",
+		},
+		{
+			name:        "Not owner",
+			userID:      2,
+			wantContent: "",
+		},
+		{
+			name:        "Owner",
+			userID:      1,
+			wantContent: content,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			if tt.userID != 0 {
+				ts.get(t, fmt.Sprintf("/test/login/%d", tt.userID))
+			}
+
+			assert.Equal(t, listedContent(t, ts), tt.wantContent)
+		})
+	}
+
+	t.Run("Unlocked", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		_, _, body := ts.get(t, "/snippet/view/Wf8kN3pXs6J")
+
+		form := url.Values{}
+		form.Add("password", "pa55word")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+		code, _, _ := ts.postForm(t, "/snippet/unlock/Wf8kN3pXs6J", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+
+		assert.Equal(t, listedContent(t, ts), content)
+	})
+}
+
+func TestAPISnippetGet(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	tests := []struct {
+		name        string
+		slug        string
+		wantCode    int
+		wantContent string
+	}{
+		{
+			name:        "Public snippet",
+			slug:        "q7Yx2LpK0aZ",
+			wantCode:    http.StatusOK,
+			wantContent: "An old silent pond...",
+		},
+		{
+			name:     "Private snippet",
+			slug:     "Vn3_cR8sWd-",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Burned snippet",
+			slug:     "Xd7pL1mNv8C",
+			wantCode: http.StatusGone,
+		},
+		{
+			name:     "Password protected snippet",
+			slug:     "Pw9rT2kLm4Q",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent snippet",
+			slug:     "aaaaaaaaaaa",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, "/api/v1/snippets/"+tt.slug)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantContent != "" {
+				var res struct {
+					Snippet apiSnippet `json:"snippet"`
+				}
+				decodeJSON(t, body, &res)
+				assert.Equal(t, res.Snippet.Content, tt.wantContent)
+				assert.Equal(t, res.Snippet.Slug, tt.slug)
+			}
+		})
+	}
+
+	t.Run("Unknown path", func(t *testing.T) {
+		code, header, _ := ts.get(t, "/api/v1/nothing")
+
+		assert.Equal(t, code, http.StatusNotFound)
+		assert.Equal(t, header.Get("Content-Type"), "application/json")
+	})
+}
+
+func TestAPISnippetCreate(t *testing.T) {
+	app := newTestApplication(t)
+
+	t.Run("Anonymous", func(t *testing.T) {
+		ts := newTestServer(t, withTestLogin(app))
+		defer ts.Close()
+
+		code, header, body := ts.request(t, http.MethodPost, "/api/v1/snippets", `{"title": "Title"}`, http.Header{
+			"Content-Type": {"application/json"},
+		})
+
+		assert.Equal(t, code, http.StatusUnauthorized)
+		assert.Equal(t, header.Get("WWW-Authenticate"), "Bearer")
+
+		var res apiErrorResponse
+		decodeJSON(t, body, &res)
+		assert.StringContains(t, res.Error.Message, "must be authenticated")
+	})
+
+	t.Run("Missing CSRF token", func(t *testing.T) {
+		ts := newTestServer(t, withTestLogin(app))
+		defer ts.Close()
+
+		header := apiLogin(t, ts, "1")
+		header.Del("X-Csrf-Token")
+
+		code, _, _ := ts.request(t, http.MethodPost, "/api/v1/snippets", `{"title": "Title"}`, header)
+		assert.Equal(t, code, http.StatusForbidden)
+	})
+
+	t.Run("Bearer token", func(t *testing.T) {
+		ts := newTestServer(t, withTestLogin(app))
+		defer ts.Close()
+
+		// A request with a bearer token isn't authenticated by the session
+		// cookie, so an invalid token is rejected even for a logged in user.
+		ts.get(t, "/test/login/1")
+
+		code, header, _ := ts.request(t, http.MethodPost, "/api/v1/snippets", `{"title": "Title"}`, http.Header{
+			"Authorization": {"Bearer not-a-token"},
+		})
+		assert.Equal(t, code, http.StatusUnauthorized)
+		assert.StringContains(t, header.Get("WWW-Authenticate"), "Bearer")
+	})
+
+	tests := []struct {
+		name         string
+		body         string
+		wantCode     int
+		wantLocation string
+		wantTags     string
+		wantFields   []string
+	}{
+		{
+			name:         "Valid submission",
+			body:         `{"title": "O snail", "content": "Climb Mount Fuji", "tags": ["haiku"], "visibility": "public"}`,
+			wantCode:     http.StatusCreated,
+			wantLocation: "/api/v1/snippets/Hm4tE9wQz1B",
+		},
+		{
+			name:         "Duplicate tags",
+			body:         `{"title": "O snail", "content": "Climb Mount Fuji", "tags": ["Haiku", "go", "haiku", "go"], "visibility": "public"}`,
+			wantCode:     http.StatusCreated,
+			wantLocation: "/api/v1/snippets/Hm4tE9wQz1B",
+			wantTags:     "[haiku go]",
+		},
+		{
+			name:         "Expires",
+			body:         `{"title": "O snail", "content": "Climb Mount Fuji", "expires": "` + time.Now().AddDate(1, 0, 0).Format(time.RFC3339) + `", "visibility": "public"}`,
+			wantCode:     http.StatusCreated,
+			wantLocation: "/api/v1/snippets/Hm4tE9wQz1B",
+		},
+		{
+			name:       "Invalid fields",
+			body:       `{"title": "", "content": "", "expires": "2000-01-01T00:00:00Z", "tags": ["NOT A TAG"], "visibility": "everyone", "password": "short"}`,
+			wantCode:   http.StatusUnprocessableEntity,
+			wantFields: []string{"title", "content", "expires", "tags", "visibility", "password"},
+		},
+		{
+			name:     "Badly-formed JSON",
+			body:     `{"title": "O snail",`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown field",
+			body:     `{"title": "O snail", "author": "Issa"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Wrong type",
+			body:     `{"title": 1}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Invalid time",
+			body:     `{"expires": "tomorrow"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Empty body",
+			body:     ``,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			header := apiLogin(t, ts, "1")
+
+			code, resHeader, body := ts.request(t, http.MethodPost, "/api/v1/snippets", tt.body, header)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, resHeader.Get("Location"), tt.wantLocation)
+
+			if code == http.StatusCreated {
+				var res struct {
+					Snippet apiSnippet `json:"snippet"`
+				}
+				decodeJSON(t, body, &res)
+				assert.Equal(t, res.Snippet.Title, "O snail")
+				if tt.wantTags != "" {
+					assert.Equal(t, fmt.Sprint(res.Snippet.Tags), tt.wantTags)
+				}
+				return
+			}
+
+			var res apiErrorResponse
+			decodeJSON(t, body, &res)
+			assert.Equal(t, res.Error.Message != "", true)
+			assert.Equal(t, len(res.Error.Fields), len(tt.wantFields))
+			for _, field := range tt.wantFields {
+				assert.Equal(t, res.Error.Fields[field] != "", true)
+			}
+		})
+	}
+}
+
+func TestAPISnippetUpdate(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name     string
+		userID   string
+		slug     string
+		body     string
+		wantCode int
+	}{
+		{
+			name:     "Valid submission",
+			userID:   "1",
+			slug:     "q7Yx2LpK0aZ",
+			body:     `{"title": "An old silent pond", "content": "A frog jumps into the pond"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Blank title",
+			userID:   "1",
+			slug:     "q7Yx2LpK0aZ",
+			body:     `{"title": "", "content": "A frog jumps into the pond"}`,
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "Not owner",
+			userID:   "2",
+			slug:     "q7Yx2LpK0aZ",
+			body:     `{"title": "Mine now", "content": "Mine now"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Someone else's private snippet",
+			userID:   "2",
+			slug:     "Vn3_cR8sWd-",
+			body:     `{"title": "Mine now", "content": "Mine now"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Expired snippet",
+			userID:   "1",
+			slug:     "Ex4pQ7wNc2R",
+			body:     `{"title": "This week's standup notes", "content": "Still nothing to report"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Someone else's expired snippet",
+			userID:   "2",
+			slug:     "Ex4pQ7wNc2R",
+			body:     `{"title": "Mine now", "content": "Mine now"}`,
+			wantCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			header := apiLogin(t, ts, tt.userID)
+
+			code, _, _ := ts.request(t, http.MethodPut, "/api/v1/snippets/"+tt.slug, tt.body, header)
+
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
+
+func TestAPISnippetDelete(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name     string
+		userID   string
+		slug     string
+		wantCode int
+	}{
+		{
+			name:     "Owner",
+			userID:   "1",
+			slug:     "q7Yx2LpK0aZ",
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "Not owner",
+			userID:   "2",
+			slug:     "q7Yx2LpK0aZ",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent snippet",
+			userID:   "1",
+			slug:     "aaaaaaaaaaa",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Expired snippet",
+			userID:   "1",
+			slug:     "Ex4pQ7wNc2R",
+			wantCode: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			header := apiLogin(t, ts, tt.userID)
+
+			code, _, _ := ts.request(t, http.MethodDelete, "/api/v1/snippets/"+tt.slug, "", header)
+
+			assert.Equal(t, code, tt.wantCode)
+		})
+	}
+}
+
+func TestAPITokenAuthentication(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	snippet := `{"title": "O snail", "content": "Climb Mount Fuji", "visibility": "public"}`
+
+	tests := []struct {
+		name     string
+		token    string
+		method   string
+		urlPath  string
+		body     string
+		wantCode int
+	}{
+		{
+			name:     "Read own private snippet",
+			token:    "sbx_aliceReadWrite",
+			method:   http.MethodGet,
+			urlPath:  "/api/v1/snippets/Vn3_cR8sWd-",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Read someone else's private snippet",
+			token:    "sbx_bobReadOnly",
+			method:   http.MethodGet,
+			urlPath:  "/api/v1/snippets/Vn3_cR8sWd-",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Create without a CSRF token",
+			token:    "sbx_aliceReadWrite",
+			method:   http.MethodPost,
+			urlPath:  "/api/v1/snippets",
+			body:     snippet,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "Create with a read only token",
+			token:    "sbx_bobReadOnly",
+			method:   http.MethodPost,
+			urlPath:  "/api/v1/snippets",
+			body:     snippet,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Invalid token",
+			token:    "sbx_revoked",
+			method:   http.MethodGet,
+			urlPath:  "/api/v1/snippets",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.request(t, tt.method, tt.urlPath, tt.body, http.Header{
+				"Authorization": {"Bearer " + tt.token},
+				"Content-Type":  {"application/json"},
+			})
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if code >= 400 {
+				var res apiErrorResponse
+				decodeJSON(t, body, &res)
+				assert.Equal(t, res.Error.Message != "", true)
+			}
+		})
+	}
+}
@@ -3,3 +3,7 @@ package main
 type contextKey string
 
 const isAuthenticatedContextKey = contextKey("isAuthenticated")
+
+// authenticatedUserIDContextKey holds the ID of the authenticated user, whether
+// they were authenticated by their session or by an API token.
+const authenticatedUserIDContextKey = contextKey("authenticatedUserID")
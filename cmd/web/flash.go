@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"encoding/gob"
+)
+
+// 提示消息的级别，决定它在页面上的样式
+const (
+	flashSuccess = "success"
+	flashWarning = "warning"
+	flashError   = "error"
+)
+
+// flash 是一条提示消息，在下一个渲染的页面顶部显示一次
+type flash struct {
+	Level   string
+	Message string
+}
+
+// flashesKey is the session key of the queue of flash messages waiting to be
+// shown.
+const flashesKey = "flashes"
+
+func init() {
+	// The session data is gob encoded, so any type other than the basic ones
+	// which is stored in it has to be registered with gob first.
+	gob.Register([]flash{})
+}
+
+// addFlash 把一条提示消息加入会话的队列，队列中的消息会在下一个页面中一起显示
+func (app *application) addFlash(ctx context.Context, level, message string) {
+	flashes, _ := app.sessionManager.Get(ctx, flashesKey).([]flash)
+	app.sessionManager.Put(ctx, flashesKey, append(flashes, flash{Level: level, Message: message}))
+}
+
+// popFlashes 返回并清空会话中等待显示的提示消息，按加入的顺序排列
+func (app *application) popFlashes(ctx context.Context) []flash {
+	flashes, _ := app.sessionManager.Pop(ctx, flashesKey).([]flash)
+
+	// Sessions created before flashes had levels hold a single plain string
+	// under the "flash" key, which is shown as a success message.
+	if message := app.sessionManager.PopString(ctx, "flash"); message != "" {
+		flashes = append([]flash{{Level: flashSuccess, Message: message}}, flashes...)
+	}
+
+	return flashes
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+func TestFlashes(t *testing.T) {
+	app := newTestApplication(t)
+
+	ctx, err := app.sessionManager.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.addFlash(ctx, flashSuccess, "Snippet successfully created!")
+	app.addFlash(ctx, flashWarning, "Careful now")
+
+	// Committing and loading the session again checks that the queue survives
+	// being gob encoded by the session store.
+	token, _, err := app.sessionManager.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err = app.sessionManager.Load(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(app.popFlashes(ctx)), 2)
+
+	// Popping the flashes empties the queue.
+	assert.Equal(t, len(app.popFlashes(ctx)), 0)
+
+	t.Run("Order", func(t *testing.T) {
+		app.addFlash(ctx, flashError, "First")
+		app.addFlash(ctx, flashSuccess, "Second")
+
+		flashes := app.popFlashes(ctx)
+		assert.Equal(t, len(flashes), 2)
+		assert.Equal(t, flashes[0], flash{Level: flashError, Message: "First"})
+		assert.Equal(t, flashes[1], flash{Level: flashSuccess, Message: "Second"})
+	})
+
+	t.Run("Plain string flash", func(t *testing.T) {
+		app.sessionManager.Put(ctx, "flash", "Old style")
+		app.addFlash(ctx, flashWarning, "New style")
+
+		flashes := app.popFlashes(ctx)
+		assert.Equal(t, len(flashes), 2)
+		assert.Equal(t, flashes[0], flash{Level: flashSuccess, Message: "Old style"})
+		assert.Equal(t, flashes[1], flash{Level: flashWarning, Message: "New style"})
+		assert.Equal(t, app.sessionManager.Exists(ctx, "flash"), false)
+	})
+}
+
+func TestFlashesRendered(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	ts.get(t, "/test/login/1")
+
+	_, _, body := ts.get(t, "/snippet/create")
+	csrfToken := extractCSRFToken(t, body)
+
+	code, header, _ := ts.postForm(t, "/snippet/create", url.Values{
+		"title":      {"Burn me"},
+		"content":    {"echo secret"},
+		"expires":    {"never"},
+		"visibility": {"unlisted"},
+		"burn":       {"true"},
+		"csrf_token": {csrfToken},
+	})
+	assert.Equal(t, code, http.StatusSeeOther)
+
+	// Both queued messages are shown on the next page, in order and with
+	// their levels.
+	_, _, body = ts.get(t, header.Get("Location"))
+
+	success := "<div class='flash flash-success'>Snippet successfully created!</div>"
+	warning := "<div class='flash flash-warning'>This snippet will be destroyed the first time someone else views it.</div>"
+	assert.StringContains(t, body, success)
+	assert.StringContains(t, body, warning)
+	assert.Equal(t, strings.Index(body, success) < strings.Index(body, warning), true)
+
+	// And only on that page.
+	_, _, body = ts.get(t, "/")
+	assert.Equal(t, strings.Contains(body, "class='flash"), false)
+}
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
+	"snippetbox.xmxxmx.us/internal/diff"
 	"snippetbox.xmxxmx.us/internal/models"
 	"snippetbox.xmxxmx.us/internal/validator"
 )
@@ -19,12 +22,104 @@ import (
 // input with the name "title" in the Title field. The struct tag `form:"-"`
 // tells the decoder to completely ignore a field during decoding.
 type snippetCreateForm struct {
+	Title   string `form:"title"`
+	Content string `form:"content"`
+	expiryForm
+	Tags                string `form:"tags"`
+	Language            string `form:"language"`
+	Visibility          string `form:"visibility"`
+	BurnAfterReading    bool   `form:"burn"`
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+// expiryForm holds the expiry fields shared by the create snippet and
+// snippet expiry forms. Expires says how the expiry is given: as a duration
+// from now ("duration"), an exact date and time in UTC ("at"), or not at all
+// ("never").
+type expiryForm struct {
+	Expires     string `form:"expires"`
+	ExpiresIn   int    `form:"expires_in"`
+	ExpiresUnit string `form:"expires_unit"`
+	ExpiresAt   string `form:"expires_at"`
+}
+
+// snippetExpiryForm holds the new expiry of an existing snippet.
+type snippetExpiryForm struct {
+	expiryForm
+	validator.Validator `form:"-"`
+}
+
+// snippetUnlockForm holds the access password entered for a password
+// protected snippet.
+type snippetUnlockForm struct {
+	Password            string `form:"password"`
+	validator.Validator `form:"-"`
+}
+
+// maxTags is the maximum number of tags which can be attached to a snippet.
+const maxTags = 5
+
+// Create a new snippetListForm struct to hold the pagination cursors and
+// filters from the home page query string. The dates are kept as strings so
+// that an invalid value can be redisplayed to the user.
+type snippetListForm struct {
+	Before              int    `form:"before"`
+	After               int    `form:"after"`
+	Size                int    `form:"size"`
+	From                string `form:"from"`
+	To                  string `form:"to"`
+	validator.Validator `form:"-"`
+}
+
+// Create a new searchForm struct to hold the search terms and page number
+// from the search page query string.
+type searchForm struct {
+	Query               string `form:"q"`
+	Page                int    `form:"page"`
+	validator.Validator `form:"-"`
+}
+
+// Create a new snippetEditForm struct. Editing only allows the title and
+// content to be changed, the expiry is changed with snippetExpiryForm.
+type snippetEditForm struct {
 	Title               string `form:"title"`
 	Content             string `form:"content"`
-	Expires             int    `form:"expires"`
 	validator.Validator `form:"-"`
 }
 
+// snippetDiffForm holds the numbers of the two revisions to compare from the
+// diff page query string. Zero means the latest revision for To, and the one
+// before To for From.
+type snippetDiffForm struct {
+	From int `form:"from"`
+	To   int `form:"to"`
+}
+
+// snippetRestoreForm holds the number of the revision to restore.
+type snippetRestoreForm struct {
+	Revision int `form:"revision"`
+}
+
+// apiTokenForm holds the name, scopes and lifetime of a new API token.
+// ExpiresIn is the number of days until the token expires, or 0 for a token
+// which never expires.
+type apiTokenForm struct {
+	Name                string   `form:"name"`
+	Scopes              []string `form:"scopes"`
+	ExpiresIn           int      `form:"expires_in"`
+	validator.Validator `form:"-"`
+}
+
+// HasScope reports whether the scope is ticked on the form.
+func (f apiTokenForm) HasScope(scope string) bool {
+	return slices.Contains(f.Scopes, scope)
+}
+
+// tokenLifetimes lists the number of days an API token can be valid for, in
+// the order they are offered on the tokens page. 0 means forever.
+var tokenLifetimes = []int{30, 90, 365, 0}
+
 // Create a new userSignupForm struct.
 type userSignupForm struct {
 	Name                string `form:"name"`
@@ -42,17 +137,58 @@ type userLoginForm struct {
 
 // home 首页处理器
 func (app *application) home(w http.ResponseWriter, r *http.Request) {
-	snippets, err := app.snippets.Latest()
+	// Decode the pagination cursors and filters from the query string. Unlike
+	// the POST forms, a value which can't be decoded (like ?size=foo) is
+	// treated as a bad request.
+	var form snippetListForm
+
+	err := app.formDecoder.Decode(&form, r.URL.Query())
 	if err != nil {
-		app.serverError(w, r, err)
+		app.clientError(w, http.StatusBadRequest)
 		return
 	}
 
+	from, fromErr := parseDate(form.From)
+	to, toErr := parseDate(form.To)
+
+	form.CheckField(form.Before >= 0 && form.After >= 0, "cursor", "Invalid page cursor")
+	form.CheckField(form.Size >= 0 && form.Size <= models.MaxPageSize, "size", fmt.Sprintf("This field must be between 1 and %d", models.MaxPageSize))
+	form.CheckField(fromErr == nil, "from", "This field must be a valid date")
+	form.CheckField(toErr == nil, "to", "This field must be a valid date")
+	form.CheckField(from.IsZero() || to.IsZero() || !to.Before(from), "to", "This field must not be before the from date")
+
 	// Call the newTemplateData() helper to get a templateData struct containing
 	// the 'default' data (which for now is just the current year), and add the
-	// snippets slice to it.
+	// form to it so that the filters are redisplayed.
 	data := app.newTemplateData(r)
+	data.Form = form
+
+	if !form.Valid() {
+		app.render(w, r, http.StatusUnprocessableEntity, "home.tmpl", data)
+		return
+	}
+
+	// The "to" date is inclusive, so we filter on snippets created before the
+	// start of the following day.
+	filter := models.SnippetFilter{
+		Before:      form.Before,
+		After:       form.After,
+		PageSize:    form.Size,
+		CreatedFrom: from,
+	}
+	if !to.IsZero() {
+		filter.CreatedTo = to.AddDate(0, 0, 1)
+	}
+
+	snippets, page, err := app.snippets.List(filter)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
 	data.Snippets = snippets
+	data.NextPageURL = pageURL(r, "before", page.NextCursor)
+	data.PrevPageURL = pageURL(r, "after", page.PrevCursor)
 
 	// Use the new render helper.
 	app.render(w, r, http.StatusOK, "home.tmpl", data)
@@ -60,17 +196,19 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
 
 // snippetView 查看代码片段处理器
 func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
-	// 提取并验证 ID 参数
-	id, err := strconv.Atoi(r.PathValue("id"))
-	if err != nil || id < 1 {
-		http.NotFound(w, r)
+	slug := r.PathValue("slug")
+
+	// Links created before snippets had slugs use the integer ID. We still
+	// accept those, but only to redirect to the canonical slug URL.
+	if id, err := strconv.Atoi(slug); err == nil {
+		app.snippetViewLegacy(w, r, id)
 		return
 	}
 
-	// Use the SnippetModel's Get() method to retrieve the data for a
-	// specific record based on its ID. If no matching record is found,
+	// Use the SnippetModel's GetBySlug() method to retrieve the data for a
+	// specific record based on its slug. If no matching record is found,
 	// return a 404 Not Found response.
-	snnipet, err := app.snippets.Get(id)
+	snnipet, err := app.snippets.GetBySlug(slug)
 	if err != nil {
 		if errors.Is(err, models.ErrNoRecord) {
 			http.NotFound(w, r)
@@ -80,6 +218,64 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Private snippets can only be viewed by their owner. We return a 404
+	// rather than a 403 so that we don't reveal that the snippet exists.
+	if !snnipet.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// A burned snippet has no content left to show, so rather than a plain
+	// 404 we tell the visitor what happened to it.
+	if snnipet.Burned {
+		app.render(w, r, http.StatusGone, "burned.tmpl", app.newTemplateData(r))
+		return
+	}
+
+	// Password protected snippets stay locked until the visitor has entered
+	// the password. This has to happen before a burn after reading snippet is
+	// burned below, otherwise it would be destroyed without being read.
+	if app.snippetLocked(r, snnipet) {
+		data := app.newTemplateData(r)
+		data.Snippet = models.Snippet{Slug: snnipet.Slug}
+		data.Form = snippetUnlockForm{}
+		app.render(w, r, http.StatusOK, "unlock.tmpl", data)
+		return
+	}
+
+	// Burn after reading snippets are destroyed the first time someone other
+	// than their owner views them. Burn() reads the content and destroys it
+	// in a single transaction, so if somebody else beat us to it we get
+	// ErrBurned back and show the burned page instead.
+	if snnipet.BurnAfterReading && snnipet.UserID != app.authenticatedUserID(r) {
+		// The route also answers HEAD requests, which link checkers and chat
+		// apps make when fetching a preview of a link. They mustn't destroy
+		// the snippet before the recipient has read it, so they only get the
+		// headers.
+		if r.Method == http.MethodHead {
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		snnipet, err = app.snippets.Burn(snnipet.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrBurned):
+				app.render(w, r, http.StatusGone, "burned.tmpl", app.newTemplateData(r))
+			case errors.Is(err, models.ErrNoRecord):
+				http.NotFound(w, r)
+			default:
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		// This is the only time the content will ever be served, so make
+		// sure that it isn't kept in any caches along the way.
+		w.Header().Set("Cache-Control", "no-store")
+	}
+
 	// Use the PopString() method to retrieve the value for the "flash" key.
 	// PopString() also deletes the key and value from the session data, so it
 	// behaves like a one-time fetch. If there is no matching key in the session
@@ -89,6 +285,21 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Snippet = snnipet
 
+	// Link to the snippet this one was forked from, but only if it's public
+	// or belongs to the viewer, so that a fork doesn't give away the link to
+	// an unlisted or private snippet. If it has expired we just leave the
+	// link out.
+	if snnipet.ForkedFrom != 0 {
+		source, err := app.snippets.Get(snnipet.ForkedFrom)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			app.serverError(w, r, err)
+			return
+		}
+		if err == nil && (source.Visibility == models.VisibilityPublic || source.UserID == app.authenticatedUserID(r)) {
+			data.ForkSource = source
+		}
+	}
+
 	// Pass the flash message to the template.
 	//data.Flash = flash
 
@@ -96,6 +307,145 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 	app.render(w, r, http.StatusOK, "view.tmpl", data)
 }
 
+// snippetViewLegacy 将使用整数 ID 的旧链接永久重定向到对应的 slug 链接
+func (app *application) snippetViewLegacy(w http.ResponseWriter, r *http.Request, id int) {
+	if id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	snippet, err := app.snippets.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Only public snippets were ever meant to be reachable by guessing their
+	// ID, so for anything else we don't give the slug away to anyone but the
+	// owner.
+	if snippet.Visibility != models.VisibilityPublic && snippet.UserID != app.authenticatedUserID(r) {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusMovedPermanently)
+}
+
+// snippetUnlockPost 检查受密码保护的代码片段的访问密码，通过后在会话中记住解锁状态
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+	snippet, err := app.snippets.GetBySlug(r.PathValue("slug"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if !snippet.VisibleTo(app.authenticatedUserID(r)) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// There's nothing to unlock if the snippet isn't protected (or has been
+	// burned), so just send the visitor to the snippet page.
+	if !snippet.Protected || snippet.Burned {
+		http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+		return
+	}
+
+	var form snippetUnlockForm
+
+	err = app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = models.Snippet{Slug: snippet.Slug}
+
+	// Limit the number of wrong passwords each session can enter, so that the
+	// password can't be brute forced.
+	if !app.unlockAttemptsLeft(r) {
+		form.AddNonFieldError("Too many incorrect passwords. Please try again later.")
+		data.Form = form
+		app.render(w, r, http.StatusTooManyRequests, "unlock.tmpl", data)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Password), "password", "This field cannot be blank")
+
+	if !form.Valid() {
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "unlock.tmpl", data)
+		return
+	}
+
+	err = app.snippets.CheckPassword(snippet.ID, form.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidCredentials):
+			app.recordFailedUnlock(r)
+			form.AddNonFieldError("Password is incorrect")
+			data.Form = form
+			app.render(w, r, http.StatusUnprocessableEntity, "unlock.tmpl", data)
+		case errors.Is(err, models.ErrNoRecord):
+			http.NotFound(w, r)
+		default:
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.sessionManager.Put(r.Context(), unlockKey(snippet.ID), true)
+
+	http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+}
+
+// snippetForkPost 把一个代码片段复制为当前用户的新片段，然后打开新片段的编辑页面
+func (app *application) snippetForkPost(w http.ResponseWriter, r *http.Request) {
+	source, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// The fork starts off as a copy of the source snippet with the same
+	// visibility, but without its password or burn after reading setting,
+	// and expires a year from now like a newly created snippet.
+	visibility := source.Visibility
+
+	// Without the password, a public or unlisted fork of a protected snippet
+	// would give its content away to anyone, so those forks are private.
+	if source.Protected {
+		visibility = models.VisibilityPrivate
+	}
+
+	id, _, err := app.snippets.Insert(models.NewSnippet{
+		Title:      source.Title,
+		Content:    source.Content,
+		Expires:    time.Now().Add(365 * 24 * time.Hour),
+		UserID:     app.authenticatedUserID(r),
+		Tags:       source.Tags,
+		Language:   source.Language,
+		Visibility: visibility,
+		ForkedFrom: source.ID,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.addFlash(r.Context(), flashSuccess, "Snippet successfully forked!")
+
+	http.Redirect(w, r, fmt.Sprintf("/snippet/edit/%d", id), http.StatusSeeOther)
+}
+
 // snippetCreate 创建代码片段表单处理器
 func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	//w.Write([]byte("Display a form for creating a new snippet..."))
@@ -104,9 +454,16 @@ func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
 	// Initialize a new snippetCreateForm instance and pass it to the template.
 	// Notice how this is also a great opportunity to set any default or
 	// 'initial' values for the form --- here we set the initial value for the
-	// snippet expiry to 365 days.
+	// snippet expiry to 365 days, auto-detect the language and make the
+	// snippet public.
 	data.Form = snippetCreateForm{
-		Expires: 365,
+		expiryForm: expiryForm{
+			Expires:     "duration",
+			ExpiresIn:   365,
+			ExpiresUnit: "days",
+		},
+		Language:   autoDetectLanguage,
+		Visibility: models.VisibilityPublic,
 	}
 
 	app.render(w, r, http.StatusOK, "create.tmpl", data)
@@ -136,7 +493,16 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
 	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
 	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-	form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+	expires := checkExpiry(&form.Validator, form.expiryForm, time.Now())
+
+	// The tags are entered as a single comma or space separated string, so we
+	// split them up before validating each one.
+	tags := parseTags(form.Tags)
+	form.CheckField(validator.MaxItems(tags, maxTags), "tags", fmt.Sprintf("This field cannot contain more than %d tags", maxTags))
+	form.CheckField(validator.AllMatch(tags, validator.TagRX), "tags", "Tags can only contain letters, digits and the characters + # . _ - and be at most 30 characters long")
+	form.CheckField(validator.PermittedValue(form.Language, append(languageValues(), autoDetectLanguage)...), "language", "This field must be one of the listed languages")
+	form.CheckField(validator.PermittedValue(form.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must equal public, unlisted or private")
+	form.CheckField(form.Password == "" || validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long")
 
 	// Use the Valid() method to see if any of the checks failed. If they did,
 	// then re-render the template passing in the form in the same way as
@@ -148,20 +514,43 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// If the user asked for the language to be auto-detected, work it out
+	// now so that the detected language is stored with the snippet.
+	language := form.Language
+	if language == autoDetectLanguage {
+		language = detectLanguage(form.Content)
+	}
+
 	// Pass the data to the SnippetModel.Insert() method, receiving the
-	// ID of the new record back.
-	id, err := app.snippets.Insert(form.Title, form.Title, form.Expires)
+	// slug of the new record back. The ID of the logged-in user is recorded as
+	// the owner of the new snippet.
+	_, slug, err := app.snippets.Insert(models.NewSnippet{
+		Title:      form.Title,
+		Content:    form.Content,
+		Expires:    expires,
+		UserID:     app.authenticatedUserID(r),
+		Tags:       tags,
+		Language:   language,
+		Visibility: form.Visibility,
+
+		BurnAfterReading: form.BurnAfterReading,
+		Password:         form.Password,
+	})
 	if err != nil {
 		app.serverError(w, r, err)
 		return
 	}
 
-	// Use the Put() method to add a string value ("Snippet successfully
-	// created!") and the corresponding key ("flash") to the session data.
-	app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+	// Queue a success message to show on the next page. A burn after reading
+	// snippet also gets a warning, because the owner only has this one chance
+	// to copy its link before sharing it.
+	app.addFlash(r.Context(), flashSuccess, "Snippet successfully created!")
+	if form.BurnAfterReading {
+		app.addFlash(r.Context(), flashWarning, "This snippet will be destroyed the first time someone else views it.")
+	}
 
 	// Redirect the user to the relevant page for the snippet.
-	http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+	http.Redirect(w, r, "/snippet/view/"+slug, http.StatusSeeOther)
 
 	// 设置 201 状态码
 	//w.WriteHeader(http.StatusCreated) // w.WriteHeader(201)
@@ -170,6 +559,492 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 	//w.Write([]byte("Save a new snippet..."))
 }
 
+// snippetEdit 编辑代码片段表单处理器
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// There's nothing left to edit once a snippet has been burned.
+	if snippet.Burned {
+		app.clientError(w, http.StatusGone)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	// Pre-populate the form with the current values of the snippet.
+	data.Form = snippetEditForm{
+		Title:   snippet.Title,
+		Content: snippet.Content,
+	}
+
+	app.render(w, r, http.StatusOK, "edit.tmpl", data)
+}
+
+// snippetEditPost 处理编辑代码片段请求
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	// There's nothing left to edit once a snippet has been burned.
+	if snippet.Burned {
+		app.clientError(w, http.StatusGone)
+		return
+	}
+
+	var form snippetEditForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+	form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "edit.tmpl", data)
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, app.authenticatedUserID(r), form.Title, form.Content)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.addFlash(r.Context(), flashSuccess, "Snippet successfully updated!")
+
+	http.Redirect(w, r, ownedSnippetPath(snippet), http.StatusSeeOther)
+}
+
+// snippetExpiry 修改代码片段过期时间的表单处理器
+func (app *application) snippetExpiry(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	if snippet.Burned {
+		app.clientError(w, http.StatusGone)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+
+	// Suggest extending the snippet by another week.
+	data.Form = snippetExpiryForm{
+		expiryForm: expiryForm{
+			Expires:     "duration",
+			ExpiresIn:   7,
+			ExpiresUnit: "days",
+		},
+	}
+
+	app.render(w, r, http.StatusOK, "expiry.tmpl", data)
+}
+
+// snippetExpiryPost 处理延长代码片段过期时间的请求
+func (app *application) snippetExpiryPost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	if snippet.Burned {
+		app.clientError(w, http.StatusGone)
+		return
+	}
+
+	var form snippetExpiryForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	expires := checkExpiry(&form.Validator, form.expiryForm, time.Now())
+
+	// Owners can extend the expiry of their snippets, but not bring it
+	// forward. A zero time means that the snippet never expires, which is
+	// later than any other expiry.
+	extends := expires.IsZero() || (!snippet.Expires.IsZero() && expires.After(snippet.Expires))
+	form.CheckField(extends, "expires", "This field must be later than the current expiry")
+
+	if !form.Valid() {
+		data := app.newTemplateData(r)
+		data.Snippet = snippet
+		data.Form = form
+		app.render(w, r, http.StatusUnprocessableEntity, "expiry.tmpl", data)
+		return
+	}
+
+	err = app.snippets.UpdateExpiry(snippet.ID, expires)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.addFlash(r.Context(), flashSuccess, "Snippet expiry successfully extended!")
+
+	http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+}
+
+// snippetRaw 以纯文本形式返回代码片段的内容，不带任何 HTML
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	serveSnippetContent(w, r, snippet, "")
+}
+
+// snippetDownload 把代码片段的内容作为文件下载，文件名由标题和语言决定
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	serveSnippetContent(w, r, snippet, downloadFilename(snippet))
+}
+
+// snippetHistory 显示代码片段的全部修订
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.Revisions = revisions
+
+	app.render(w, r, http.StatusOK, "history.tmpl", data)
+}
+
+// snippetDiff 显示代码片段两个修订之间的差异
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.readableSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	var form snippetDiffForm
+
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := app.snippets.Revisions(snippet.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// The revisions are numbered from 1 and returned newest first, so the
+	// latest revision's number is also the number of revisions.
+	if form.To == 0 && len(revisions) > 0 {
+		form.To = revisions[0].Number
+	}
+	if form.From == 0 {
+		form.From = form.To - 1
+	}
+
+	// Look up both revisions, returning a 404 if either doesn't exist.
+	var from, to models.Revision
+	for _, revision := range revisions {
+		switch revision.Number {
+		case form.From:
+			from = revision
+		case form.To:
+			to = revision
+		}
+	}
+	if from.Number == 0 || to.Number == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippet = snippet
+	data.FromRevision = from
+	data.ToRevision = to
+	data.Diff = diff.Hunks(from.Content, to.Content, 3)
+
+	app.render(w, r, http.StatusOK, "diff.tmpl", data)
+}
+
+// snippetRestorePost 把代码片段恢复到之前的某个修订，恢复本身会成为一个新的修订
+func (app *application) snippetRestorePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	if snippet.Burned {
+		app.clientError(w, http.StatusGone)
+		return
+	}
+
+	var form snippetRestoreForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	revision, err := app.snippets.Revision(snippet.ID, form.Revision)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.snippets.Update(snippet.ID, app.authenticatedUserID(r), revision.Title, revision.Content)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.addFlash(r.Context(), flashSuccess, fmt.Sprintf("Snippet successfully restored to revision #%d!", revision.Number))
+
+	http.Redirect(w, r, ownedSnippetPath(snippet), http.StatusSeeOther)
+}
+
+// snippetDeletePost 处理删除代码片段请求
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+	snippet, ok := app.ownedSnippet(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.snippets.Delete(snippet.ID)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.addFlash(r.Context(), flashSuccess, "Snippet successfully deleted!")
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// tagView 按标签列出代码片段处理器
+func (app *application) tagView(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("name")
+	if !validator.Matches(tag, validator.TagRX) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Only the pagination cursors from the query string are used here.
+	var form snippetListForm
+
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil || form.Before < 0 || form.After < 0 {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	snippets, page, err := app.snippets.List(models.SnippetFilter{
+		Before: form.Before,
+		After:  form.After,
+		Tag:    tag,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Tag = tag
+	data.Snippets = snippets
+	data.NextPageURL = pageURL(r, "before", page.NextCursor)
+	data.PrevPageURL = pageURL(r, "after", page.PrevCursor)
+
+	app.render(w, r, http.StatusOK, "tag.tmpl", data)
+}
+
+// search 全文搜索代码片段处理器
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+	var form searchForm
+
+	err := app.formDecoder.Decode(&form, r.URL.Query())
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.MaxChars(form.Query, 100), "q", "This field cannot be more than 100 characters long")
+	form.CheckField(form.Page >= 0, "page", "Invalid page number")
+
+	data := app.newTemplateData(r)
+	data.Form = form
+
+	if !form.Valid() {
+		app.render(w, r, http.StatusUnprocessableEntity, "search.tmpl", data)
+		return
+	}
+
+	// If no search terms have been entered then just display the search form.
+	if !validator.NotBlank(form.Query) {
+		app.render(w, r, http.StatusOK, "search.tmpl", data)
+		return
+	}
+
+	snippets, page, err := app.snippets.Search(form.Query, form.Page, models.DefaultPageSize)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data.Snippets = snippets
+	data.NextPageURL = pageURL(r, "page", page.NextCursor)
+	data.PrevPageURL = pageURL(r, "page", page.PrevCursor)
+
+	app.render(w, r, http.StatusOK, "search.tmpl", data)
+}
+
+// userSnippets 当前用户的代码片段列表处理器
+func (app *application) userSnippets(w http.ResponseWriter, r *http.Request) {
+	// Read the sort order from the query string, falling back to newest first
+	// if it's missing or isn't one of the permitted values.
+	sort := r.URL.Query().Get("sort")
+	if !validator.PermittedValue(sort, models.UserSnippetSorts...) {
+		sort = models.UserSnippetSorts[0]
+	}
+
+	snippets, err := app.snippets.ForUser(app.authenticatedUserID(r), sort)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data := app.newTemplateData(r)
+	data.Snippets = snippets
+	data.Sort = sort
+
+	app.render(w, r, http.StatusOK, "snippets.tmpl", data)
+}
+
+// userTokens 显示当前用户的 API 令牌以及创建新令牌的表单
+func (app *application) userTokens(w http.ResponseWriter, r *http.Request) {
+	data := app.newTemplateData(r)
+	data.Form = apiTokenForm{Scopes: []string{models.ScopeRead}, ExpiresIn: tokenLifetimes[0]}
+
+	app.renderTokens(w, r, http.StatusOK, data)
+}
+
+// userTokensPost 创建新的 API 令牌。令牌本身只在这个响应中显示一次，
+// 所以这里直接渲染页面而不是重定向。
+func (app *application) userTokensPost(w http.ResponseWriter, r *http.Request) {
+	var form apiTokenForm
+
+	err := app.decodePostForm(r, &form)
+	if err != nil {
+		app.clientError(w, http.StatusBadRequest)
+		return
+	}
+
+	form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+	form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+	form.CheckField(len(form.Scopes) > 0, "scopes", "You must pick at least one scope")
+	for _, scope := range form.Scopes {
+		form.CheckField(validator.PermittedValue(scope, models.ScopeRead, models.ScopeWrite), "scopes", "Scopes must be read or write")
+	}
+	form.CheckField(validator.PermittedValue(form.ExpiresIn, tokenLifetimes...), "expires_in", "This field must be one of the listed lifetimes")
+
+	data := app.newTemplateData(r)
+
+	if !form.Valid() {
+		data.Form = form
+		app.renderTokens(w, r, http.StatusUnprocessableEntity, data)
+		return
+	}
+
+	var expires time.Time
+	if form.ExpiresIn > 0 {
+		expires = time.Now().AddDate(0, 0, form.ExpiresIn)
+	}
+
+	token, err := app.tokens.Insert(app.authenticatedUserID(r), form.Name, form.Scopes, expires)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data.NewToken = token
+	data.Form = apiTokenForm{Scopes: []string{models.ScopeRead}, ExpiresIn: tokenLifetimes[0]}
+
+	app.renderTokens(w, r, http.StatusCreated, data)
+}
+
+// userTokenRevokePost 撤销当前用户的一个 API 令牌
+func (app *application) userTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = app.tokens.Delete(id, app.authenticatedUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	app.addFlash(r.Context(), flashSuccess, "API token successfully revoked!")
+
+	http.Redirect(w, r, "/user/tokens", http.StatusSeeOther)
+}
+
+// renderTokens loads the current user's API tokens into data and renders the
+// tokens page with them.
+func (app *application) renderTokens(w http.ResponseWriter, r *http.Request, status int, data templateData) {
+	tokens, err := app.tokens.ForUser(app.authenticatedUserID(r))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	data.Tokens = tokens
+	app.render(w, r, status, "tokens.tmpl", data)
+}
+
 func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
 	data := app.newTemplateData(r)
 	data.Form = userSignupForm{}
@@ -222,7 +1097,7 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 
 	// Otherwise add a confirmation flash message to the session confirming that
 	// their signup worked.
-	app.sessionManager.Put(r.Context(), "flash", "Your signup was successful. Please log in.")
+	app.addFlash(r.Context(), flashSuccess, "Your signup was successful. Please log in.")
 
 	// And redirect the user to the login page.
 	http.Redirect(w, r, "/user/login", http.StatusSeeOther)
@@ -268,6 +1143,12 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 			data := app.newTemplateData(r)
 			data.Form = form
 			app.render(w, r, http.StatusUnprocessableEntity, "login.tmpl", data)
+		} else if errors.Is(err, models.ErrAccountDisabled) {
+			form.AddNonFieldError("Your account has been disabled")
+
+			data := app.newTemplateData(r)
+			data.Form = form
+			app.render(w, r, http.StatusForbidden, "login.tmpl", data)
 		} else {
 			app.serverError(w, r, err)
 		}
@@ -288,8 +1169,8 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
 	// 'logged in'.
 	app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
 
-	// Redirect the user to the create snippet page.
-	http.Redirect(w, r, "/snippet/create", http.StatusSeeOther)
+	// Redirect the user to their snippets dashboard.
+	http.Redirect(w, r, "/user/snippets", http.StatusSeeOther)
 }
 
 func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
@@ -307,7 +1188,7 @@ func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
 
 	// Add a flash message to the session to confirm to the user that they've been
 	// logged out.
-	app.sessionManager.Put(r.Context(), "flash", "You've been logged out successfully!")
+	app.addFlash(r.Context(), flashSuccess, "You've been logged out successfully!")
 
 	// Redirect the user to the home page.
 	http.Redirect(w, r, "/", http.StatusSeeOther)
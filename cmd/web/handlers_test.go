@@ -1,11 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"snippetbox.xmxxmx.us/internal/assert"
+	"snippetbox.xmxxmx.us/internal/models"
 )
 
 func TestPing(t *testing.T) {
@@ -64,17 +67,34 @@ func TestSnippetView(t *testing.T) {
 	// Set up some table-driven tests to check the responses sent by our
 	// application for different URLs.
 	tests := []struct {
-		name     string
-		urlPath  string
-		wantCode int
-		wantBody string
+		name         string
+		urlPath      string
+		wantCode     int
+		wantBody     string
+		wantLocation string
 	}{
 		{
-			name:     "Valid ID",
-			urlPath:  "/snippet/view/1",
+			name:     "Valid slug",
+			urlPath:  "/snippet/view/q7Yx2LpK0aZ",
 			wantCode: http.StatusOK,
 			wantBody: "An old silent pond...",
 		},
+		{
+			name:     "Non-existent slug",
+			urlPath:  "/snippet/view/xxxxxxxxxxx",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Legacy ID",
+			urlPath:      "/snippet/view/1",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/snippet/view/q7Yx2LpK0aZ",
+		},
+		{
+			name:     "Legacy ID of private snippet",
+			urlPath:  "/snippet/view/3",
+			wantCode: http.StatusNotFound,
+		},
 		{
 			name:     "Non-existent ID",
 			urlPath:  "/snippet/view/2",
@@ -90,11 +110,6 @@ func TestSnippetView(t *testing.T) {
 			urlPath:  "/snippet/view/1.23",
 			wantCode: http.StatusNotFound,
 		},
-		{
-			name:     "String ID",
-			urlPath:  "/snippet/view/foo",
-			wantCode: http.StatusNotFound,
-		},
 		{
 			name:     "Empty ID",
 			urlPath:  "/snippet/view/",
@@ -104,9 +119,10 @@ func TestSnippetView(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			code, _, body := ts.get(t, tt.urlPath)
+			code, header, body := ts.get(t, tt.urlPath)
 
 			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
 
 			if tt.wantBody != "" {
 				assert.StringContains(t, body, tt.wantBody)
@@ -238,3 +254,1359 @@ func TestUserSignup(t *testing.T) {
 	// the provided message to the test output.
 	// t.Logf("CSRF token is: %q", csrfToken)
 }
+
+func TestUserLogin(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	_, _, body := ts.get(t, "/user/login")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	const formTag = "<form action='/user/login' method='POST' novalidate>"
+
+	tests := []struct {
+		name         string
+		userEmail    string
+		userPassword string
+		csrfToken    string
+		wantCode     int
+		wantLocation string
+		wantBody     []string
+	}{
+		{
+			name:         "Valid submission",
+			userEmail:    "alice@example.com",
+			userPassword: "pa$$word",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/user/snippets",
+		},
+		{
+			name:         "Invalid CSRF Token",
+			userEmail:    "alice@example.com",
+			userPassword: "pa$$word",
+			csrfToken:    "wrongToken",
+			wantCode:     http.StatusBadRequest,
+		},
+		{
+			name:         "Empty email",
+			userEmail:    "",
+			userPassword: "pa$$word",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantBody:     []string{formTag, "This field cannot be blank"},
+		},
+		{
+			name:         "Invalid email",
+			userEmail:    "alice@example.",
+			userPassword: "pa$$word",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantBody: []string{
+				"This field must be a valid email address",
+				"<input type='email' name='email' value='alice@example.'>",
+			},
+		},
+		{
+			name:         "Empty password",
+			userEmail:    "alice@example.com",
+			userPassword: "",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantBody:     []string{"<label class='error'>This field cannot be blank</label>"},
+		},
+		{
+			name:         "Wrong password",
+			userEmail:    "alice@example.com",
+			userPassword: "wrongPa$$word",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusUnprocessableEntity,
+			wantBody: []string{
+				"<div class='error'>Email or password is incorrect</div>",
+				"<input type='email' name='email' value='alice@example.com'>",
+			},
+		},
+		{
+			name:         "Disabled account",
+			userEmail:    "carol@example.com",
+			userPassword: "pa$$word",
+			csrfToken:    validCSRFToken,
+			wantCode:     http.StatusForbidden,
+			wantBody:     []string{"<div class='error'>Your account has been disabled</div>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("email", tt.userEmail)
+			form.Add("password", tt.userPassword)
+			form.Add("csrf_token", tt.csrfToken)
+
+			code, header, body := ts.postForm(t, "/user/login", form)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+
+			for _, want := range tt.wantBody {
+				assert.StringContains(t, body, want)
+			}
+
+			// The password is never sent back to the browser.
+			if tt.userPassword != "" {
+				assert.Equal(t, strings.Contains(body, tt.userPassword), false)
+			}
+		})
+	}
+}
+
+func TestUserSignupLoginLogout(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	// An invalid signup redisplays the form with the errors and the values
+	// that were entered, apart from the password.
+	_, _, body := ts.get(t, "/user/signup")
+	csrfToken := extractCSRFToken(t, body)
+
+	code, _, body := ts.postForm(t, "/user/signup", url.Values{
+		"name":       {"Bob"},
+		"email":      {"dupe@example.com"},
+		"password":   {"pa$$"},
+		"csrf_token": {csrfToken},
+	})
+	assert.Equal(t, code, http.StatusUnprocessableEntity)
+	assert.StringContains(t, body, "<input type='text' name='name' value='Bob'>")
+	assert.StringContains(t, body, "<input type='email' name='email' value='dupe@example.com'>")
+	assert.StringContains(t, body, "This field must be at least 8 characters long")
+
+	// Signing up redirects to the login page, which shows a flash message.
+	code, header, _ := ts.postForm(t, "/user/signup", url.Values{
+		"name":       {"Alice"},
+		"email":      {"alice@example.com"},
+		"password":   {"pa$$word"},
+		"csrf_token": {csrfToken},
+	})
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/user/login")
+
+	code, _, body = ts.get(t, "/user/login")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "Your signup was successful. Please log in.")
+	csrfToken = extractCSRFToken(t, body)
+
+	// Logging in redirects to the user's snippets, and the navigation now
+	// has a logout button instead of the login link.
+	code, header, _ = ts.postForm(t, "/user/login", url.Values{
+		"email":      {"alice@example.com"},
+		"password":   {"pa$$word"},
+		"csrf_token": {csrfToken},
+	})
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/user/snippets")
+
+	code, _, body = ts.get(t, "/user/snippets")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "<button>Logout</button>")
+	assert.Equal(t, strings.Contains(body, "<a href='/user/login'>Login</a>"), false)
+	csrfToken = extractCSRFToken(t, body)
+
+	// Logging out redirects home with a flash message, after which the
+	// protected pages redirect to the login page again.
+	code, header, _ = ts.postForm(t, "/user/logout", url.Values{
+		"csrf_token": {csrfToken},
+	})
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/")
+
+	code, _, body = ts.get(t, "/")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "You&#39;ve been logged out successfully!")
+	assert.StringContains(t, body, "<a href='/user/login'>Login</a>")
+
+	code, header, _ = ts.get(t, "/user/snippets")
+	assert.Equal(t, code, http.StatusSeeOther)
+	assert.Equal(t, header.Get("Location"), "/user/login")
+}
+
+func TestSnippetEdit(t *testing.T) {
+	app := newTestApplication(t)
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		code, header, _ := ts.get(t, "/snippet/edit/1")
+
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("Location"), "/user/login")
+	})
+
+	tests := []struct {
+		name     string
+		userID   int
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Owner",
+			userID:   1,
+			urlPath:  "/snippet/edit/1",
+			wantCode: http.StatusOK,
+			wantBody: "<form action='/snippet/edit/1' method='POST'>",
+		},
+		{
+			name:     "Not owner",
+			userID:   2,
+			urlPath:  "/snippet/edit/1",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Non-existent ID",
+			userID:   1,
+			urlPath:  "/snippet/edit/2",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Expired owner",
+			userID:   1,
+			urlPath:  "/snippet/edit/7",
+			wantCode: http.StatusOK,
+			wantBody: "<form action='/snippet/edit/7' method='POST'>",
+		},
+		{
+			name:     "Expired not owner",
+			userID:   2,
+			urlPath:  "/snippet/edit/7",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "Expired expiry form",
+			userID:   1,
+			urlPath:  "/snippet/expiry/7",
+			wantCode: http.StatusOK,
+			wantBody: "This snippet expired on",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			ts.get(t, fmt.Sprintf("/test/login/%d", tt.userID))
+
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+
+	t.Run("Expired post", func(t *testing.T) {
+		ts := newTestServer(t, withTestLogin(app))
+		defer ts.Close()
+
+		ts.get(t, "/test/login/1")
+
+		_, _, body := ts.get(t, "/snippet/edit/7")
+
+		form := url.Values{}
+		form.Add("title", "This week's standup notes")
+		form.Add("content", "Still nothing to report")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+
+		code, header, _ := ts.postForm(t, "/snippet/edit/7", form)
+
+		// The expired snippet can't be viewed, so the owner is sent back to
+		// their list of snippets instead.
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("Location"), "/user/snippets")
+	})
+}
+
+func TestSnippetDeletePost(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name         string
+		userID       int
+		urlPath      string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Owner",
+			userID:       1,
+			urlPath:      "/snippet/delete/1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/",
+		},
+		{
+			name:     "Not owner",
+			userID:   2,
+			urlPath:  "/snippet/delete/1",
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:         "Expired",
+			userID:       1,
+			urlPath:      "/snippet/delete/7",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			ts.get(t, fmt.Sprintf("/test/login/%d", tt.userID))
+
+			// Any page rendered for a logged-in user contains a CSRF token
+			// in the logout form.
+			_, _, body := ts.get(t, "/snippet/view/q7Yx2LpK0aZ")
+
+			form := url.Values{}
+			form.Add("csrf_token", extractCSRFToken(t, body))
+
+			code, header, _ := ts.postForm(t, tt.urlPath, form)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+		})
+	}
+}
+
+func TestUserSnippets(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name     string
+		userID   int
+		wantBody string
+	}{
+		{
+			name:     "With snippets",
+			userID:   1,
+			wantBody: "An old silent pond",
+		},
+		{
+			name:     "Expired snippet actions",
+			userID:   1,
+			wantBody: "<a href='/snippet/expiry/7'>Renew</a>",
+		},
+		{
+			name:     "Without snippets",
+			userID:   2,
+			wantBody: "You haven't created any snippets yet.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			ts.get(t, fmt.Sprintf("/test/login/%d", tt.userID))
+
+			code, _, body := ts.get(t, "/user/snippets?sort=expires")
+
+			assert.Equal(t, code, http.StatusOK)
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+}
+
+func TestHome(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "No filters",
+			urlPath:  "/",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond",
+		},
+		{
+			name:     "Valid filters",
+			urlPath:  "/?from=2025-01-01&to=2025-01-31&size=25&before=10",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond",
+		},
+		{
+			name:     "Invalid date",
+			urlPath:  "/?from=2025-13-01",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be a valid date",
+		},
+		{
+			name:     "Reversed dates",
+			urlPath:  "/?from=2025-02-01&to=2025-01-01",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must not be before the from date",
+		},
+		{
+			name:     "Page size too large",
+			urlPath:  "/?size=1000",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must be between 1 and 100",
+		},
+		{
+			name:     "Non-numeric cursor",
+			urlPath:  "/?before=foo",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "No query",
+			urlPath:  "/search",
+			wantCode: http.StatusOK,
+			wantBody: "<form class='filter search' action='/search' method='GET'>",
+		},
+		{
+			name:     "Matching query",
+			urlPath:  "/search?q=silent",
+			wantCode: http.StatusOK,
+			wantBody: "An old <mark>silent</mark> pond...",
+		},
+		{
+			name:     "No matches",
+			urlPath:  "/search?q=nginx",
+			wantCode: http.StatusOK,
+			wantBody: "No snippets matched your search.",
+		},
+		{
+			name:     "Protected content",
+			urlPath:  "/search?q=hunter2",
+			wantCode: http.StatusOK,
+			wantBody: "No snippets matched your search.",
+		},
+		{
+			name:     "Invalid page",
+			urlPath:  "/search?q=silent&page=foo",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetCreatePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	ts.get(t, "/test/login/1")
+
+	_, _, body := ts.get(t, "/snippet/create")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		title        string
+		content      string
+		expires      string
+		expiresIn    string
+		expiresUnit  string
+		expiresAt    string
+		tags         string
+		language     string
+		visibility   string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Valid submission",
+			title:        "Tagged",
+			content:      "echo hello",
+			expires:      "duration",
+			expiresIn:    "90",
+			expiresUnit:  "minutes",
+			tags:         "Bash, shell k8s",
+			visibility:   "public",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/Hm4tE9wQz1B",
+		},
+		{
+			name:     "Empty title",
+			title:    "",
+			content:  "echo hello",
+			expires:  "never",
+			wantCode: http.StatusBadRequest,
+			wantBody: "This field cannot be blank",
+		},
+		{
+			name:         "Never expires",
+			title:        "Forever",
+			content:      "echo hello",
+			expires:      "never",
+			visibility:   "public",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/Hm4tE9wQz1B",
+		},
+		{
+			name:      "Expiry in the past",
+			title:     "Expired",
+			content:   "echo hello",
+			expires:   "at",
+			expiresAt: "2020-01-01T10:00",
+			wantCode:  http.StatusBadRequest,
+			wantBody:  "This field must be between now and 10 years from now",
+		},
+		{
+			name:        "Expiry too far away",
+			title:       "Forever",
+			content:     "echo hello",
+			expires:     "duration",
+			expiresIn:   "5000",
+			expiresUnit: "days",
+			wantCode:    http.StatusBadRequest,
+			wantBody:    "This field must be between 1 minute and 10 years",
+		},
+		{
+			name:        "Invalid expiry unit",
+			title:       "Forever",
+			content:     "echo hello",
+			expires:     "duration",
+			expiresIn:   "3",
+			expiresUnit: "weeks",
+			wantCode:    http.StatusBadRequest,
+			wantBody:    "This field must be in minutes, hours or days",
+		},
+		{
+			name:     "Too many tags",
+			title:    "Tagged",
+			content:  "echo hello",
+			expires:  "never",
+			tags:     "a b c d e f",
+			wantCode: http.StatusBadRequest,
+			wantBody: "This field cannot contain more than 5 tags",
+		},
+		{
+			name:     "Invalid tag",
+			title:    "Tagged",
+			content:  "echo hello",
+			expires:  "never",
+			tags:     "bash <script>",
+			wantCode: http.StatusBadRequest,
+			wantBody: "Tags can only contain letters, digits",
+		},
+		{
+			name:     "Invalid language",
+			title:    "Tagged",
+			content:  "echo hello",
+			expires:  "never",
+			language: "cobol",
+			wantCode: http.StatusBadRequest,
+			wantBody: "This field must be one of the listed languages",
+		},
+		{
+			name:       "Invalid visibility",
+			title:      "Tagged",
+			content:    "echo hello",
+			expires:    "never",
+			visibility: "secret",
+			wantCode:   http.StatusBadRequest,
+			wantBody:   "This field must equal public, unlisted or private",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("title", tt.title)
+			form.Add("content", tt.content)
+			form.Add("expires", tt.expires)
+			form.Add("expires_in", tt.expiresIn)
+			form.Add("expires_unit", tt.expiresUnit)
+			form.Add("expires_at", tt.expiresAt)
+			form.Add("tags", tt.tags)
+			form.Add("language", tt.language)
+			form.Add("visibility", tt.visibility)
+			form.Add("csrf_token", validCSRFToken)
+
+			code, header, body := ts.postForm(t, "/snippet/create", form)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTagView(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Tag with snippets",
+			urlPath:  "/tag/poetry",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond",
+		},
+		{
+			name:     "Tag without snippets",
+			urlPath:  "/tag/k8s",
+			wantCode: http.StatusOK,
+			wantBody: "There are no snippets with this tag.",
+		},
+		{
+			name:     "Escaped tag",
+			urlPath:  "/tag/c%23",
+			wantCode: http.StatusOK,
+			wantBody: "Snippets tagged <span class='tag'>c#</span>",
+		},
+		{
+			name:     "Invalid tag",
+			urlPath:  "/tag/Not%20A%20Tag",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetViewPrivate(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name     string
+		userID   int
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Anonymous",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Owner",
+			userID:   1,
+			wantCode: http.StatusOK,
+			wantBody: "A frog jumps in...",
+		},
+		{
+			name:     "Not owner",
+			userID:   2,
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			if tt.userID != 0 {
+				ts.get(t, fmt.Sprintf("/test/login/%d", tt.userID))
+			}
+
+			code, _, body := ts.get(t, "/snippet/view/Vn3_cR8sWd-")
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetViewBurnAfterReading(t *testing.T) {
+	app := newTestApplication(t)
+
+	tests := []struct {
+		name             string
+		userID           int
+		urlPath          string
+		wantCode         int
+		wantBody         string
+		wantCacheControl string
+	}{
+		{
+			name:             "Anonymous",
+			urlPath:          "/snippet/view/Bz0kT5rYq2M",
+			wantCode:         http.StatusOK,
+			wantBody:         "correct horse battery staple",
+			wantCacheControl: "no-store",
+		},
+		{
+			name:     "Owner",
+			userID:   1,
+			urlPath:  "/snippet/view/Bz0kT5rYq2M",
+			wantCode: http.StatusOK,
+			wantBody: "correct horse battery staple",
+		},
+		{
+			name:     "Already burned",
+			urlPath:  "/snippet/view/Xd7pL1mNv8C",
+			wantCode: http.StatusGone,
+			wantBody: "viewed and destroyed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			if tt.userID != 0 {
+				ts.get(t, fmt.Sprintf("/test/login/%d", tt.userID))
+			}
+
+			code, header, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Cache-Control"), tt.wantCacheControl)
+			assert.StringContains(t, body, tt.wantBody)
+		})
+	}
+
+	t.Run("HEAD request", func(t *testing.T) {
+		ts := newTestServer(t, newTestApplication(t).routes())
+		defer ts.Close()
+
+		code, header, body := ts.request(t, http.MethodHead, "/snippet/view/Bz0kT5rYq2M", "", nil)
+		assert.Equal(t, code, http.StatusOK)
+		assert.Equal(t, header.Get("Cache-Control"), "no-store")
+		assert.Equal(t, body, "")
+
+		// The snippet hasn't been burned, so it can still be read once.
+		code, _, body = ts.get(t, "/snippet/view/Bz0kT5rYq2M")
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "correct horse battery staple")
+
+		code, _, _ = ts.get(t, "/snippet/view/Bz0kT5rYq2M")
+		assert.Equal(t, code, http.StatusGone)
+	})
+}
+
+func TestSnippetUnlock(t *testing.T) {
+	app := newTestApplication(t)
+
+	const viewPath = "/snippet/view/Pw9rT2kLm4Q"
+	const unlockPath = "/snippet/unlock/Pw9rT2kLm4Q"
+
+	t.Run("Owner", func(t *testing.T) {
+		ts := newTestServer(t, withTestLogin(app))
+		defer ts.Close()
+
+		ts.get(t, "/test/login/1")
+
+		code, _, body := ts.get(t, viewPath)
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "Meet at the old pond at dawn")
+	})
+
+	t.Run("Unlock", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		code, _, body := ts.get(t, viewPath)
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "This snippet is password protected")
+		csrfToken := extractCSRFToken(t, body)
+
+		form := url.Values{}
+		form.Add("password", "wrong password")
+		form.Add("csrf_token", csrfToken)
+		code, _, body = ts.postForm(t, unlockPath, form)
+		assert.Equal(t, code, http.StatusUnprocessableEntity)
+		assert.StringContains(t, body, "Password is incorrect")
+
+		form.Set("password", "pa55word")
+		code, header, _ := ts.postForm(t, unlockPath, form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("Location"), viewPath)
+
+		// The unlock is remembered for the rest of the session.
+		code, _, body = ts.get(t, viewPath)
+		assert.Equal(t, code, http.StatusOK)
+		assert.StringContains(t, body, "Meet at the old pond at dawn")
+	})
+
+	t.Run("Rate limited", func(t *testing.T) {
+		ts := newTestServer(t, app.routes())
+		defer ts.Close()
+
+		_, _, body := ts.get(t, viewPath)
+
+		form := url.Values{}
+		form.Add("password", "wrong password")
+		form.Add("csrf_token", extractCSRFToken(t, body))
+
+		for range maxUnlockAttempts {
+			code, _, _ := ts.postForm(t, unlockPath, form)
+			assert.Equal(t, code, http.StatusUnprocessableEntity)
+		}
+
+		// Once the attempts are used up even the right password is refused.
+		form.Set("password", "pa55word")
+		code, _, body := ts.postForm(t, unlockPath, form)
+		assert.Equal(t, code, http.StatusTooManyRequests)
+		assert.StringContains(t, body, "Too many incorrect passwords")
+	})
+}
+
+func TestSnippetExpiryPost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	ts.get(t, "/test/login/1")
+
+	_, _, body := ts.get(t, "/snippet/expiry/1")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		expires      string
+		expiresIn    string
+		expiresUnit  string
+		wantCode     int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Extend",
+			expires:      "duration",
+			expiresIn:    "2",
+			expiresUnit:  "hours",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/q7Yx2LpK0aZ",
+		},
+		{
+			name:         "Never",
+			expires:      "never",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/q7Yx2LpK0aZ",
+		},
+		{
+			name:     "Invalid",
+			expires:  "tomorrow",
+			wantCode: http.StatusUnprocessableEntity,
+			wantBody: "This field must equal duration, at or never",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("expires", tt.expires)
+			form.Add("expires_in", tt.expiresIn)
+			form.Add("expires_unit", tt.expiresUnit)
+			form.Add("csrf_token", validCSRFToken)
+
+			code, header, body := ts.postForm(t, "/snippet/expiry/1", form)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetHistory(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name         string
+		urlPath      string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:     "History",
+			urlPath:  "/snippet/history/q7Yx2LpK0aZ",
+			wantCode: http.StatusOK,
+			wantBody: "An old pond",
+		},
+		{
+			name:     "Diff",
+			urlPath:  "/snippet/diff/q7Yx2LpK0aZ",
+			wantCode: http.StatusOK,
+			wantBody: "-An old pond...",
+		},
+		{
+			name:     "Diff between chosen revisions",
+			urlPath:  "/snippet/diff/q7Yx2LpK0aZ?from=2&to=1",
+			wantCode: http.StatusOK,
+			wantBody: "&#43;An old pond...",
+		},
+		{
+			name:     "Non-existent revision",
+			urlPath:  "/snippet/diff/q7Yx2LpK0aZ?from=1&to=5",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Private snippet",
+			urlPath:  "/snippet/history/Vn3_cR8sWd-",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Burn after reading snippet",
+			urlPath:  "/snippet/history/Bz0kT5rYq2M",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:         "Locked snippet",
+			urlPath:      "/snippet/history/Pw9rT2kLm4Q",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/Pw9rT2kLm4Q",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+
+			if tt.wantBody != "" {
+				assert.StringContains(t, body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSnippetRestorePost(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	ts.get(t, "/test/login/1")
+
+	// The owner is offered to restore the older revision from the diff page.
+	_, _, body := ts.get(t, "/snippet/diff/q7Yx2LpK0aZ")
+	assert.StringContains(t, body, "Restore revision #1")
+	validCSRFToken := extractCSRFToken(t, body)
+
+	tests := []struct {
+		name         string
+		revision     string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "Valid revision",
+			revision:     "1",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/q7Yx2LpK0aZ",
+		},
+		{
+			name:     "Non-existent revision",
+			revision: "9",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Invalid revision",
+			revision: "one",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			form.Add("revision", tt.revision)
+			form.Add("csrf_token", validCSRFToken)
+
+			code, header, _ := ts.postForm(t, "/snippet/restore/1", form)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+		})
+	}
+}
+
+func TestSnippetForkPost(t *testing.T) {
+	app := newTestApplication(t)
+
+	t.Run("Anonymous", func(t *testing.T) {
+		ts := newTestServer(t, withTestLogin(app))
+		defer ts.Close()
+
+		// Anyone can see how often a snippet has been forked, but only
+		// logged in users are offered the fork button.
+		_, _, body := ts.get(t, "/snippet/view/q7Yx2LpK0aZ")
+		assert.StringContains(t, body, "1 fork")
+		assert.Equal(t, strings.Contains(body, "/snippet/fork/q7Yx2LpK0aZ"), false)
+	})
+
+	tests := []struct {
+		name           string
+		userID         int
+		slug           string
+		unlock         bool
+		wantCode       int
+		wantLocation   string
+		wantVisibility string
+	}{
+		{
+			name:           "Public snippet",
+			userID:         2,
+			slug:           "q7Yx2LpK0aZ",
+			wantCode:       http.StatusSeeOther,
+			wantLocation:   "/snippet/edit/2",
+			wantVisibility: models.VisibilityPublic,
+		},
+		{
+			name:           "Own private snippet",
+			userID:         1,
+			slug:           "Vn3_cR8sWd-",
+			wantCode:       http.StatusSeeOther,
+			wantLocation:   "/snippet/edit/2",
+			wantVisibility: models.VisibilityPrivate,
+		},
+		{
+			name:         "Locked protected snippet",
+			userID:       2,
+			slug:         "Wf8kN3pXs6J",
+			wantCode:     http.StatusSeeOther,
+			wantLocation: "/snippet/view/Wf8kN3pXs6J",
+		},
+		{
+			name:           "Unlocked protected snippet",
+			userID:         2,
+			slug:           "Wf8kN3pXs6J",
+			unlock:         true,
+			wantCode:       http.StatusSeeOther,
+			wantLocation:   "/snippet/edit/2",
+			wantVisibility: models.VisibilityPrivate,
+		},
+		{
+			name:     "Someone else's private snippet",
+			userID:   2,
+			slug:     "Vn3_cR8sWd-",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Non-existent snippet",
+			userID:   2,
+			slug:     "aaaaaaaaaaa",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, withTestLogin(app))
+			defer ts.Close()
+
+			ts.get(t, fmt.Sprintf("/test/login/%d", tt.userID))
+
+			_, _, body := ts.get(t, "/snippet/view/q7Yx2LpK0aZ")
+			assert.StringContains(t, body, "/snippet/fork/q7Yx2LpK0aZ")
+			validCSRFToken := extractCSRFToken(t, body)
+
+			form := url.Values{}
+			form.Add("csrf_token", validCSRFToken)
+
+			if tt.unlock {
+				unlockForm := url.Values{}
+				unlockForm.Add("password", "pa55word")
+				unlockForm.Add("csrf_token", validCSRFToken)
+				code, _, _ := ts.postForm(t, "/snippet/unlock/"+tt.slug, unlockForm)
+				assert.Equal(t, code, http.StatusSeeOther)
+			}
+
+			code, header, _ := ts.postForm(t, "/snippet/fork/"+tt.slug, form)
+
+			assert.Equal(t, code, tt.wantCode)
+			assert.Equal(t, header.Get("Location"), tt.wantLocation)
+
+			if tt.wantVisibility != "" {
+				fork, err := app.snippets.Get(2)
+				assert.NilError(t, err)
+				assert.Equal(t, fork.Visibility, tt.wantVisibility)
+			}
+		})
+	}
+}
+
+func TestSnippetViewForkSource(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	ts.get(t, "/test/login/1")
+
+	_, _, body := ts.get(t, "/snippet/view/Vn3_cR8sWd-")
+	assert.StringContains(t, body, "Forked from <a href='/snippet/view/q7Yx2LpK0aZ'>#q7Yx2LpK0aZ</a>")
+}
+
+func TestSnippetRaw(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		urlPath  string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "Public snippet",
+			urlPath:  "/snippet/raw/q7Yx2LpK0aZ",
+			wantCode: http.StatusOK,
+			wantBody: "An old silent pond...",
+		},
+		{
+			name:     "Private snippet",
+			urlPath:  "/snippet/raw/Vn3_cR8sWd-",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Burn after reading snippet",
+			urlPath:  "/snippet/raw/Bz0kT5rYq2M",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "Burned snippet",
+			urlPath:  "/snippet/raw/Xd7pL1mNv8C",
+			wantCode: http.StatusGone,
+		},
+		{
+			name:     "Password protected snippet",
+			urlPath:  "/snippet/raw/Pw9rT2kLm4Q",
+			wantCode: http.StatusSeeOther,
+		},
+		{
+			name:     "Non-existent snippet",
+			urlPath:  "/snippet/raw/aaaaaaaaaaa",
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, header, body := ts.get(t, tt.urlPath)
+
+			assert.Equal(t, code, tt.wantCode)
+
+			if tt.wantBody != "" {
+				assert.Equal(t, body, tt.wantBody)
+				assert.Equal(t, header.Get("Content-Type"), "text/plain; charset=utf-8")
+				assert.Equal(t, header.Get("Last-Modified"), "Sat, 05 Jul 2025 18:48:00 GMT")
+			}
+		})
+	}
+
+	t.Run("Not modified", func(t *testing.T) {
+		_, header, _ := ts.get(t, "/snippet/raw/q7Yx2LpK0aZ")
+		etag := header.Get("ETag")
+		assert.Equal(t, etag != "", true)
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/snippet/raw/q7Yx2LpK0aZ", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("If-None-Match", etag)
+
+		rs, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.Body.Close()
+
+		assert.Equal(t, rs.StatusCode, http.StatusNotModified)
+	})
+}
+
+func TestSnippetDownload(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	// The owner of a password protected snippet doesn't need to unlock it.
+	ts.get(t, "/test/login/1")
+
+	code, header, body := ts.get(t, "/snippet/download/Pw9rT2kLm4Q")
+
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, body, "Meet at the old pond at dawn")
+	assert.Equal(t, header.Get("Cache-Control"), "private, no-cache")
+	assert.StringContains(t, header.Get("Content-Disposition"), "attachment")
+	assert.StringContains(t, header.Get("Content-Disposition"), ".txt")
+}
+
+func TestUserTokens(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, withTestLogin(app))
+	defer ts.Close()
+
+	ts.get(t, "/test/login/1")
+
+	code, _, body := ts.get(t, "/user/tokens")
+	assert.Equal(t, code, http.StatusOK)
+	assert.StringContains(t, body, "Laptop")
+	assert.Equal(t, strings.Contains(body, "Backups"), false)
+	validCSRFToken := extractCSRFToken(t, body)
+
+	t.Run("Create", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			tokenName string
+			scopes    []string
+			expiresIn string
+			wantCode  int
+			wantBody  string
+		}{
+			{
+				name:      "Valid submission",
+				tokenName: "Desktop",
+				scopes:    []string{"read", "write"},
+				expiresIn: "90",
+				wantCode:  http.StatusCreated,
+				wantBody:  "sbx_newTokenShownOnce",
+			},
+			{
+				name:      "Never expires",
+				tokenName: "Desktop",
+				scopes:    []string{"read"},
+				expiresIn: "0",
+				wantCode:  http.StatusCreated,
+				wantBody:  "sbx_newTokenShownOnce",
+			},
+			{
+				name:      "Blank name",
+				scopes:    []string{"read"},
+				expiresIn: "30",
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "This field cannot be blank",
+			},
+			{
+				name:      "No scopes",
+				tokenName: "Desktop",
+				expiresIn: "30",
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "You must pick at least one scope",
+			},
+			{
+				name:      "Invalid scope",
+				tokenName: "Desktop",
+				scopes:    []string{"admin"},
+				expiresIn: "30",
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "Scopes must be read or write",
+			},
+			{
+				name:      "Invalid lifetime",
+				tokenName: "Desktop",
+				scopes:    []string{"read"},
+				expiresIn: "7",
+				wantCode:  http.StatusUnprocessableEntity,
+				wantBody:  "This field must be one of the listed lifetimes",
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				form := url.Values{}
+				form.Add("name", tt.tokenName)
+				for _, scope := range tt.scopes {
+					form.Add("scopes", scope)
+				}
+				form.Add("expires_in", tt.expiresIn)
+				form.Add("csrf_token", validCSRFToken)
+
+				code, _, body := ts.postForm(t, "/user/tokens", form)
+
+				assert.Equal(t, code, tt.wantCode)
+				assert.StringContains(t, body, tt.wantBody)
+			})
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		form := url.Values{}
+		form.Add("csrf_token", validCSRFToken)
+
+		code, header, _ := ts.postForm(t, "/user/tokens/revoke/1", form)
+		assert.Equal(t, code, http.StatusSeeOther)
+		assert.Equal(t, header.Get("Location"), "/user/tokens")
+
+		// Other users' tokens can't be revoked.
+		code, _, _ = ts.postForm(t, "/user/tokens/revoke/2", form)
+		assert.Equal(t, code, http.StatusNotFound)
+	})
+}
@@ -2,10 +2,21 @@ package main
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+
+	"snippetbox.xmxxmx.us/internal/models"
+	"snippetbox.xmxxmx.us/internal/validator"
 
 	"github.com/go-playground/form/v4"
 	"github.com/justinas/nosurf"
@@ -39,15 +50,25 @@ func (app *application) render(w http.ResponseWriter, r *http.Request, status in
 		return
 	}
 
+	// Take the queued flash messages out of the session only now that the
+	// page is actually being rendered, so that they aren't lost when a
+	// handler builds its template data but then redirects or fails instead.
+	// Any flashes the handler set on data itself are shown after them.
+	data.Flashes = append(app.popFlashes(r.Context()), data.Flashes...)
+
 	// Initialize a new buffer.
 	buf := new(bytes.Buffer)
 
 	// Write the template to the buffer, instead of straight to the
-	// http.ResponseWriter. If there's an error, call our serverError() helper
-	// and then return.
+	// http.ResponseWriter. If there's an error, put the flash messages back
+	// for the next page, call our serverError() helper and then return.
 	err := ts.ExecuteTemplate(buf, "base", data)
 	if err != nil {
+		if len(data.Flashes) > 0 {
+			app.sessionManager.Put(r.Context(), flashesKey, data.Flashes)
+		}
 		app.serverError(w, r, err)
+		return
 	}
 
 	// Write out the provided HTTP status code ('200 OK', '400 Bad Request' etc).
@@ -72,11 +93,10 @@ func (app *application) render(w http.ResponseWriter, r *http.Request, status in
 func (app *application) newTemplateData(r *http.Request) templateData {
 	return templateData{
 		CurrentYear: time.Now().Year(),
-		// Add the flash message to the template data, if one exists.
-		Flash: app.sessionManager.PopString(r.Context(), "flash"),
 		// Add the authentication status to the template data.
-		IsAuthenticated: app.isAuthenticated(r),
-		CSRFToken:       nosurf.Token(r),
+		IsAuthenticated:     app.isAuthenticated(r),
+		AuthenticatedUserID: app.authenticatedUserID(r),
+		CSRFToken:           nosurf.Token(r),
 	}
 }
 
@@ -121,3 +141,295 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 	}
 	return isAuthenticated
 }
+
+// Return the ID of the current user if the request is from an authenticated
+// user, otherwise return 0.
+func (app *application) authenticatedUserID(r *http.Request) int {
+	if !app.isAuthenticated(r) {
+		return 0
+	}
+	id, _ := r.Context().Value(authenticatedUserIDContextKey).(int)
+	return id
+}
+
+// 每个会话在 unlockAttemptWindow 时间内最多可以输错 maxUnlockAttempts 次
+// 代码片段的访问密码，超过之后的尝试会被拒绝，直到时间窗口结束
+const (
+	maxUnlockAttempts   = 5
+	unlockAttemptWindow = 15 * time.Minute
+)
+
+// unlockKey returns the session key used to remember that the password
+// protected snippet with the given ID has been unlocked.
+func unlockKey(id int) string {
+	return fmt.Sprintf("unlockedSnippet:%d", id)
+}
+
+// snippetUnlocked reports whether the current session has already entered the
+// password for the snippet. The unlock is kept in the session for as long as
+// the snippet exists.
+func (app *application) snippetUnlocked(r *http.Request, snippet models.Snippet) bool {
+	return app.sessionManager.GetBool(r.Context(), unlockKey(snippet.ID))
+}
+
+// snippetLocked reports whether the snippet's content must be kept from the
+// current user: it's password protected, they don't own it and they haven't
+// unlocked it yet.
+func (app *application) snippetLocked(r *http.Request, snippet models.Snippet) bool {
+	return snippet.Protected && snippet.UserID != app.authenticatedUserID(r) && !app.snippetUnlocked(r, snippet)
+}
+
+// unlockAttemptsLeft reports whether the current session may try another
+// snippet password, starting a new attempt window if the last one has ended.
+func (app *application) unlockAttemptsLeft(r *http.Request) bool {
+	ctx := r.Context()
+
+	// The start of the window is stored as a Unix timestamp, because the
+	// session data is gob encoded and time.Time isn't registered with gob.
+	start := time.Unix(app.sessionManager.GetInt64(ctx, "unlockWindowStart"), 0)
+	if time.Since(start) > unlockAttemptWindow {
+		app.sessionManager.Put(ctx, "unlockWindowStart", time.Now().Unix())
+		app.sessionManager.Put(ctx, "unlockAttempts", 0)
+	}
+
+	return app.sessionManager.GetInt(ctx, "unlockAttempts") < maxUnlockAttempts
+}
+
+// recordFailedUnlock counts a wrong snippet password against the current
+// session's attempt window.
+func (app *application) recordFailedUnlock(r *http.Request) {
+	ctx := r.Context()
+	app.sessionManager.Put(ctx, "unlockAttempts", app.sessionManager.GetInt(ctx, "unlockAttempts")+1)
+}
+
+// readableSnippet 读取 URL 路径中 slug 对应的代码片段，供代码片段页面以外、
+// 同样会显示片段内容的页面（比如修订历史）使用。如果当前用户不能阅读该片段，
+// 响应已经写出并返回 false：其他用户的私有片段返回 404，已销毁的片段显示销毁
+// 页面，尚未解锁的受密码保护片段重定向到片段页面解锁。阅后即焚的片段只能由
+// 它的所有者通过这些页面阅读，其他人会得到 404。
+func (app *application) readableSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	snippet, err := app.snippets.GetBySlug(r.PathValue("slug"))
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	userID := app.authenticatedUserID(r)
+
+	switch {
+	case !snippet.VisibleTo(userID):
+		http.NotFound(w, r)
+	case snippet.Burned:
+		app.render(w, r, http.StatusGone, "burned.tmpl", app.newTemplateData(r))
+	case snippet.BurnAfterReading && snippet.UserID != userID:
+		http.NotFound(w, r)
+	case app.snippetLocked(r, snippet):
+		http.Redirect(w, r, "/snippet/view/"+snippet.Slug, http.StatusSeeOther)
+	default:
+		return snippet, true
+	}
+
+	return models.Snippet{}, false
+}
+
+// ownedSnippet 读取 URL 路径中 id 对应的代码片段，并检查它是否属于当前用户。
+// 已经过期的片段也会返回，这样所有者仍然可以编辑、删除它或者延长过期时间。
+// 如果片段不存在则返回 404，如果属于其他用户则返回 403，这两种情况下响应都已
+// 写出，调用方只需要在 ok 为 false 时直接返回。
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		http.NotFound(w, r)
+		return models.Snippet{}, false
+	}
+
+	snippet, err := app.snippets.GetForOwner(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			http.NotFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return models.Snippet{}, false
+	}
+
+	if snippet.UserID != app.authenticatedUserID(r) {
+		app.clientError(w, http.StatusForbidden)
+		return models.Snippet{}, false
+	}
+
+	return snippet, true
+}
+
+// ownedSnippetPath 返回所有者修改代码片段之后跳转的页面。已经过期的片段无法查看，
+// 所以这时跳转到用户自己的代码片段列表。
+func ownedSnippetPath(snippet models.Snippet) string {
+	if snippet.Expired() {
+		return "/user/snippets"
+	}
+	return "/snippet/view/" + snippet.Slug
+}
+
+// serveSnippetContent 以纯文本形式写出代码片段的内容。ETag 由内容的哈希值
+// 生成，Last-Modified 是片段最后一次修订的时间，因此客户端可以用条件请求避免
+// 重复下载没有变化的片段。filename 不为空时响应会作为附件下载。
+func serveSnippetContent(w http.ResponseWriter, r *http.Request, snippet models.Snippet, filename string) {
+	sum := sha256.Sum256([]byte(snippet.Content))
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+
+	// Caches must check with us before reusing a response, so that a snippet
+	// which has been deleted or made private stops being served. Anything
+	// other than a public snippet is marked private so that shared caches
+	// don't store it at all.
+	if snippet.Visibility == models.VisibilityPublic && !snippet.Protected {
+		w.Header().Set("Cache-Control", "no-cache")
+	} else {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+
+	if filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+
+	// http.ServeContent() takes care of If-None-Match, If-Modified-Since,
+	// Range and HEAD requests for us.
+	http.ServeContent(w, r, "", snippet.Updated, strings.NewReader(snippet.Content))
+}
+
+// maxFilenameLength limits the length of the name part of a download's file
+// name, in characters.
+const maxFilenameLength = 64
+
+// downloadFilename 根据代码片段的标题和语言生成下载时使用的文件名，例如标题为
+// "Hello, World!" 的 Go 片段会生成 "Hello-World.go"。标题中字母和数字以外的
+// 字符都被替换为连字符，如果什么都不剩就使用 "snippet"。
+func downloadFilename(snippet models.Snippet) string {
+	var sb strings.Builder
+	dash := false
+	n := 0
+
+	for _, r := range snippet.Title {
+		if n == maxFilenameLength {
+			break
+		}
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+				n++
+			}
+			sb.WriteRune(r)
+			n++
+			dash = false
+		default:
+			dash = true
+		}
+	}
+
+	name := sb.String()
+	if name == "" {
+		name = "snippet"
+	}
+
+	return name + languageExtension(snippet.Language)
+}
+
+// parseDate parses an optional date in the format used by HTML date inputs
+// (like "2025-07-05"). An empty value returns the zero time.Time and no error.
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// pageURL returns the URL for a neighbouring page of results by copying the
+// current query string (so that any filters are preserved) and replacing the
+// pagination cursor. It returns the empty string if the cursor is 0, which
+// means that there is no page in that direction.
+func pageURL(r *http.Request, key string, cursor int) string {
+	if cursor == 0 {
+		return ""
+	}
+
+	query := r.URL.Query()
+	query.Del("before")
+	query.Del("after")
+	query.Set(key, strconv.Itoa(cursor))
+
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// expiryUnits maps the units accepted for an expiry duration to their length.
+var expiryUnits = map[string]time.Duration{
+	"minutes": time.Minute,
+	"hours":   time.Hour,
+	"days":    24 * time.Hour,
+}
+
+// maxExpiry is the furthest in the future that a snippet's expiry can be set.
+// Anything longer should use "never" instead.
+const maxExpiry = 10 * 365 * 24 * time.Hour
+
+// expiryAtLayout is the format used by HTML datetime-local inputs (like
+// "2025-07-05T18:48").
+const expiryAtLayout = "2006-01-02T15:04"
+
+// checkExpiry validates the expiry fields of f, adding any errors to v, and
+// returns the expiry time which they describe. The zero time means that the
+// snippet never expires.
+func checkExpiry(v *validator.Validator, f expiryForm, now time.Time) time.Time {
+	var expires time.Time
+
+	switch f.Expires {
+	case "duration":
+		// Check the number against the unit before multiplying them, so that
+		// a huge number can't overflow the time.Duration.
+		unit, ok := expiryUnits[f.ExpiresUnit]
+		v.CheckField(ok, "expires", "This field must be in minutes, hours or days")
+		v.CheckField(!ok || (f.ExpiresIn > 0 && time.Duration(f.ExpiresIn) <= maxExpiry/unit), "expires", "This field must be between 1 minute and 10 years")
+		expires = now.Add(time.Duration(f.ExpiresIn) * unit)
+	case "at":
+		// The datetime-local input doesn't include a time zone, so the time
+		// is always entered in UTC.
+		at, err := time.ParseInLocation(expiryAtLayout, f.ExpiresAt, time.UTC)
+		v.CheckField(err == nil, "expires", "This field must be a valid date and time")
+		v.CheckField(err != nil || (at.After(now) && at.Sub(now) <= maxExpiry), "expires", "This field must be between now and 10 years from now")
+		expires = at
+	case "never":
+	default:
+		v.AddFieldError("expires", "This field must equal duration, at or never")
+	}
+
+	return expires
+}
+
+// parseTags splits a comma or space separated list of tags into a slice,
+// converting each tag to lowercase and removing any duplicates.
+func parseTags(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+
+	return normalizeTags(fields)
+}
+
+// normalizeTags converts each tag to lowercase and removes any duplicates,
+// keeping the tags in the order they were first given.
+func normalizeTags(values []string) []string {
+	var tags []string
+	for _, value := range values {
+		tag := strings.ToLower(value)
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+	"snippetbox.xmxxmx.us/internal/models"
+)
+
+func TestDownloadFilename(t *testing.T) {
+	tests := []struct {
+		name     string
+		title    string
+		language string
+		want     string
+	}{
+		{
+			name:     "Go",
+			title:    "Hello, World!",
+			language: "go",
+			want:     "Hello-World.go",
+		},
+		{
+			name:  "Plain text",
+			title: "An old silent pond",
+			want:  "An-old-silent-pond.txt",
+		},
+		{
+			name:     "Non-ASCII title",
+			title:    "古池や 蛙飛び込む",
+			language: "python",
+			want:     "古池や-蛙飛び込む.py",
+		},
+		{
+			name:  "Nothing left of the title",
+			title: "../../",
+			want:  "snippet.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := downloadFilename(models.Snippet{Title: tt.title, Language: tt.language})
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// autoDetectLanguage is the value of the "auto-detect" option on the create
+// snippet form.
+const autoDetectLanguage = "auto"
+
+// Define a language type to hold the value and label for each option in the
+// language select box. The value is the chroma lexer alias which is stored in
+// the database, and an empty value means plain text.
+type language struct {
+	Value string
+	Label string
+}
+
+// languages lists the languages which can be picked on the create snippet
+// form, in the order they are displayed.
+var languages = []language{
+	{Value: "", Label: "Plain text"},
+	{Value: "bash", Label: "Bash"},
+	{Value: "c", Label: "C"},
+	{Value: "cpp", Label: "C++"},
+	{Value: "csharp", Label: "C#"},
+	{Value: "css", Label: "CSS"},
+	{Value: "diff", Label: "Diff"},
+	{Value: "docker", Label: "Dockerfile"},
+	{Value: "go", Label: "Go"},
+	{Value: "html", Label: "HTML"},
+	{Value: "ini", Label: "INI"},
+	{Value: "java", Label: "Java"},
+	{Value: "js", Label: "JavaScript"},
+	{Value: "json", Label: "JSON"},
+	{Value: "makefile", Label: "Makefile"},
+	{Value: "markdown", Label: "Markdown"},
+	{Value: "nginx", Label: "Nginx"},
+	{Value: "php", Label: "PHP"},
+	{Value: "powershell", Label: "PowerShell"},
+	{Value: "python", Label: "Python"},
+	{Value: "ruby", Label: "Ruby"},
+	{Value: "rust", Label: "Rust"},
+	{Value: "sql", Label: "SQL"},
+	{Value: "toml", Label: "TOML"},
+	{Value: "ts", Label: "TypeScript"},
+	{Value: "yaml", Label: "YAML"},
+}
+
+// languageValues returns the values of all the selectable languages, for use
+// with validator.PermittedValue().
+func languageValues() []string {
+	values := make([]string, len(languages))
+	for i, l := range languages {
+		values[i] = l.Value
+	}
+	return values
+}
+
+// languageName returns a human-readable name for a stored language value.
+func languageName(value string) string {
+	for _, l := range languages {
+		if l.Value == value {
+			return l.Label
+		}
+	}
+
+	// Auto-detection can pick a lexer which isn't in the languages list, so
+	// fall back to asking chroma for its name.
+	if lexer := lexers.Get(value); lexer != nil {
+		return lexer.Config().Name
+	}
+
+	return "Plain text"
+}
+
+// languageExtension returns the file name extension, including the dot, for
+// a stored language value. It's taken from the first plain "*.ext" pattern in
+// the chroma lexer's list of file names, and is ".txt" for plain text or if
+// the lexer doesn't have one.
+func languageExtension(value string) string {
+	if lexer := lexers.Get(value); lexer != nil && value != "" {
+		for _, pattern := range lexer.Config().Filenames {
+			ext, ok := strings.CutPrefix(pattern, "*")
+			if ok && strings.HasPrefix(ext, ".") && !strings.ContainsAny(ext, "*?[") {
+				return ext
+			}
+		}
+	}
+
+	return ".txt"
+}
+
+// detectLanguage guesses the language of some code, returning the empty
+// string (plain text) if it can't be worked out.
+func detectLanguage(code string) string {
+	lexer := lexers.Analyse(code)
+	if lexer == nil {
+		return ""
+	}
+
+	config := lexer.Config()
+	if len(config.Aliases) > 0 {
+		return config.Aliases[0]
+	}
+	return strings.ToLower(config.Name)
+}
+
+// The formatter uses CSS classes rather than inline styles, because inline
+// styles are blocked by the Content-Security-Policy header set in
+// commonHeaders. The stylesheet ui/static/css/highlight.css was generated from
+// highlightStyle with the formatter's WriteCSS() method.
+var (
+	highlightFormatter = html.New(html.WithClasses(true))
+	highlightStyle     = styles.Get("github")
+)
+
+// highlight returns the code as syntax highlighted HTML for the given
+// language. If the code can't be highlighted it is returned HTML-escaped in a
+// plain <pre><code> block instead.
+func highlight(code, language string) template.HTML {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return plainCode(code)
+	}
+
+	var buf bytes.Buffer
+	err = highlightFormatter.Format(&buf, highlightStyle, iterator)
+	if err != nil {
+		return plainCode(code)
+	}
+
+	return template.HTML(buf.String())
+}
+
+func plainCode(code string) template.HTML {
+	return template.HTML("<pre><code>" + template.HTMLEscapeString(code) + "</code></pre>")
+}
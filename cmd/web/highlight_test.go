@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		language string
+		want     string
+	}{
+		{
+			name:     "Go",
+			code:     "package main",
+			language: "go",
+			want:     `<span class="kn">package</span>`,
+		},
+		{
+			name:     "Plain text",
+			code:     "<b>not bold</b>",
+			language: "",
+			want:     "&lt;b&gt;not bold&lt;/b&gt;",
+		},
+		{
+			name:     "Unknown language",
+			code:     "<b>not bold</b>",
+			language: "not-a-language",
+			want:     "&lt;b&gt;not bold&lt;/b&gt;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.StringContains(t, string(highlight(tt.code, tt.language)), tt.want)
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{name: "Shebang", code: "#!/bin/bash\necho hello", want: "bash"},
+		{name: "Unknown", code: "An old silent pond...", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, detectLanguage(tt.code), tt.want)
+		})
+	}
+}
+
+func TestLanguageExtension(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{language: "go", want: ".go"},
+		{language: "python", want: ".py"},
+		{language: "cpp", want: ".cpp"},
+		{language: "nginx", want: ".txt"},
+		{language: "", want: ".txt"},
+		{language: "not-a-language", want: ".txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			assert.Equal(t, languageExtension(tt.language), tt.want)
+		})
+	}
+}
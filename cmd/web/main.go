@@ -1,22 +1,26 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
-	"database/sql"
 	"flag"
+	"fmt"
 	"html/template"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	// 导入我们创建的 models 包
+	"snippetbox.xmxxmx.us/internal/config"
 	"snippetbox.xmxxmx.us/internal/models"
 
 	"github.com/alexedwards/scs/mysqlstore"
+	"github.com/alexedwards/scs/sqlite3store"
 	"github.com/alexedwards/scs/v2"
 	"github.com/go-playground/form/v4"
-	_ "github.com/go-sql-driver/mysql"
 )
 
 // application 应用程序结构体，用于保存全局依赖项
@@ -29,6 +33,7 @@ type application struct {
 	// snippets       *models.SnippetModel
 	snippets       models.SnippetModelInterface
 	users          models.UserModelInterface
+	tokens         models.TokenModelInterface
 	templateCache  map[string]*template.Template
 	formDecoder    *form.Decoder
 	sessionManager *scs.SessionManager
@@ -36,18 +41,39 @@ type application struct {
 }
 
 func main() {
-	// 定义命令行参数 addr，默认值为 ":4000"
-	addr := flag.String("addr", ":4000", "HTTP network address")
-	// 定义 MySQL 数据源名称参数
-	dsn := flag.String("dsn", "snippetbox:Mz8nQ3vR7sT2uW5yE9aF4bG6cH1jL0kP@/snippetbox?parseTime=true", "MySQL data source name")
-	// 解析命令行参数，必须在使用参数前调用
-	flag.Parse()
+	// 定义 -print-config 参数，用于检查最终生效的配置
+	printConfig := flag.Bool("print-config", false, "Print the configuration, with secrets redacted, and exit")
+
+	// 加载配置：默认值、配置文件、SNIPPETBOX_* 环境变量和命令行参数，后者覆盖前者。
+	// Load() also parses the rest of the command line flags.
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:], os.Getenv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "snippetbox: %v\n", err)
+		os.Exit(2)
+	}
+
+	// The configuration is printed before it's validated, so that it can be
+	// used to work out why it's invalid.
+	if *printConfig {
+		err = cfg.Print(os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "snippetbox: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "snippetbox: invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
 
 	// 初始化结构化日志器
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	// 创建数据库连接池
-	db, err := openDB(*dsn)
+	// 创建数据库连接池，使用 SQLite 时数据库文件和表会在不存在时被创建
+	db, err := models.Open(models.Backend(cfg.DBBackend), cfg.DSN)
 	if err != nil {
 		logger.Error(err.Error())
 		os.Exit(1)
@@ -67,12 +93,16 @@ func main() {
 	formDecoder := form.NewDecoder()
 
 	// Use the scs.New() function to initialize a new session manager. Then we
-	// configure it to use our MySQL database as the session store, and set a
-	// lifetime of 12 hours (so that sessions automatically expire 12 hours
-	// after first being created).
+	// configure it to use our database as the session store, and set the
+	// configured lifetime (12 hours by default, so that sessions
+	// automatically expire 12 hours after first being created).
 	sessionManager := scs.New()
-	sessionManager.Store = mysqlstore.New(db)
-	sessionManager.Lifetime = 12 * time.Hour
+	if models.Backend(cfg.DBBackend) == models.SQLite {
+		sessionManager.Store = sqlite3store.New(db)
+	} else {
+		sessionManager.Store = mysqlstore.New(db)
+	}
+	sessionManager.Lifetime = cfg.SessionLifetime
 	// Make sure that the Secure attribute is set on our session cookies.
 	// Setting this means that the cookie will only be sent by a user's web
 	// browser when an HTTPS connection is being used (and won't be sent over an
@@ -87,6 +117,18 @@ func main() {
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
 		users:          &models.UserModel{DB: db},
+		tokens:         &models.TokenModel{DB: db},
+	}
+
+	// Create the background reaper which purges expired snippets. It's run by
+	// serve() alongside the server.
+	rp := &reaper{
+		snippets:  app.snippets,
+		logger:    logger,
+		interval:  cfg.ReaperInterval,
+		grace:     cfg.ReaperGrace,
+		batchSize: cfg.ReaperBatch,
+		now:       time.Now,
 	}
 
 	// Initialize a tls.Config struct to hold the non-default TLS settings we
@@ -100,7 +142,7 @@ func main() {
 	// Initialize a new http.Server struct. We set the Addr and Handler fields so
 	// that the server uses the same network address and routes as before.
 	srv := &http.Server{
-		Addr:    *addr,
+		Addr:    cfg.Addr,
 		Handler: app.routes(),
 		// Create a *log.Logger from our structured logger handler, which writes
 		// log entries at the Error level, and assign it to the ErrorLog field. If
@@ -108,35 +150,34 @@ func main() {
 		// could pass slog.LevelWarn as the final parameter.
 		ErrorLog:  slog.NewLogLogger(logger.Handler(), slog.LevelError),
 		TLSConfig: tlsConfig,
-		// Add Idle, Read and Write timeouts to the server.
-		IdleTimeout:  time.Minute,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		// Add the configured Idle, Read and Write timeouts to the server.
+		IdleTimeout:  cfg.IdleTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
 	}
 
+	// ctx is cancelled when the process receives SIGINT (Ctrl+C) or SIGTERM
+	// (which is what process managers send to stop it), and that starts a
+	// graceful shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	// 记录服务器启动信息
 	logger.Info("Starting server", "addr", srv.Addr)
 
-	// Call the ListenAndServe() method on our new http.Server struct to start
-	// the server.
-	err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-	// 记录错误并退出
-	logger.Error(err.Error())
-	os.Exit(1)
-}
-
-// openDB 创建并返回数据库连接池
-func openDB(dsn string) (*sql.DB, error) {
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return nil, err
-	}
-
-	err = db.Ping()
+	// Call the ListenAndServeTLS() method on our new http.Server struct to
+	// start the server, and wait for it to stop.
+	err = app.serve(ctx, srv, func() error {
+		return srv.ListenAndServeTLS(cfg.TLSCert, cfg.TLSKey)
+	}, rp, cfg.ShutdownTimeout)
 	if err != nil {
+		// 记录错误并退出，os.Exit() 不会执行延迟调用，所以先关闭数据库连接池
+		logger.Error(err.Error())
 		db.Close()
-		return nil, err
+		os.Exit(1)
 	}
 
-	return db, nil
+	// Returning from main() runs the deferred db.Close() and exits with
+	// status 0. The logger writes each entry straight to stdout, so there's
+	// nothing buffered left to flush.
 }
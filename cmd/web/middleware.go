@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"snippetbox.xmxxmx.us/internal/models"
 
 	"github.com/justinas/nosurf"
 )
@@ -119,10 +123,115 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		// create a new copy of the request (with an isAuthenticatedContextKey
 		// value of true in the request context) and assign it to r.
 		if exists {
-			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
-			r = r.WithContext(ctx)
+			r = withAuthenticatedUser(r, id)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAPIAuthentication is the JSON API's version of requireAuthentication,
+// which responds with a 401 error instead of redirecting to the login page.
+func (app *application) requireAPIAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAuthenticated(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.apiError(w, r, http.StatusUnauthorized, "you must be authenticated to access this resource")
+			return
 		}
 
+		w.Header().Add("Cache-Control", "no-store")
+
 		next.ServeHTTP(w, r)
 	})
 }
+
+// apiAuthenticate authenticates JSON API requests. Requests which carry an
+// "Authorization: Bearer" header are authenticated by their token alone and
+// skip the CSRF check, because a browser never adds that header to a forged
+// cross-site request by itself. Any other request is treated like a request
+// for a page: it's authenticated by its session cookie, so it needs a valid
+// CSRF token in the X-CSRF-Token header for anything other than GET, HEAD,
+// OPTIONS and TRACE.
+func (app *application) apiAuthenticate(next http.Handler) http.Handler {
+	csrfHandler := nosurf.New(app.authenticate(next))
+	csrfHandler.SetBaseCookie(http.Cookie{
+		HttpOnly: true,
+		Path:     "/",
+		Secure:   true,
+	})
+	csrfHandler.SetFailureHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.apiError(w, r, http.StatusForbidden, "the CSRF token is missing or invalid")
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := bearerToken(r); !ok {
+			// A request without a session cookie has no credentials which
+			// could have been sent by a forged request, so it can skip the
+			// CSRF check too. It's anonymous, and is rejected with a 401 by
+			// requireAPIAuthentication if it needs to be authenticated.
+			if _, err := r.Cookie(app.sessionManager.Cookie.Name); err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			csrfHandler.ServeHTTP(w, r)
+			return
+		}
+
+		app.authenticateToken(next).ServeHTTP(w, r)
+	})
+}
+
+// authenticateToken authenticates a request by the API token in its
+// "Authorization: Bearer" header. Unlike a session, a token which isn't valid
+// is an error rather than making the request anonymous, so that a client
+// with a revoked or expired token finds out straight away. Requests which
+// only read data need the token to have the read scope, and all other
+// requests need the write scope.
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := bearerToken(r)
+
+		t, err := app.tokens.Authenticate(token)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCredentials) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				app.apiError(w, r, http.StatusUnauthorized, "invalid or expired API token")
+			} else {
+				app.apiServerError(w, r, err)
+			}
+			return
+		}
+
+		scope := models.ScopeWrite
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			scope = models.ScopeRead
+		}
+		if !t.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+			app.apiError(w, r, http.StatusForbidden, fmt.Sprintf("the API token doesn't have the %s scope", scope))
+			return
+		}
+
+		next.ServeHTTP(w, withAuthenticatedUser(r, t.UserID))
+	})
+}
+
+// withAuthenticatedUser returns a copy of the request whose context marks it
+// as coming from the user with the given ID.
+func withAuthenticatedUser(r *http.Request, userID int) *http.Request {
+	ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+	ctx = context.WithValue(ctx, authenticatedUserIDContextKey, userID)
+	return r.WithContext(ctx)
+}
+
+// bearerToken returns the token from the request's "Authorization: Bearer"
+// header. ok is false if the request doesn't have one.
+func bearerToken(r *http.Request) (token string, ok bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
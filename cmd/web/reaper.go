@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// expiredSnippetDeleter is the part of models.SnippetModelInterface which the
+// reaper needs.
+type expiredSnippetDeleter interface {
+	DeleteExpired(before time.Time, limit int) (int, error)
+}
+
+// reaper 是定期清理过期代码片段的后台任务。片段过期后会先保留 grace 时间，
+// 然后以每批最多 batchSize 个的方式删除。now 用于获取当前时间，测试时可以替换。
+type reaper struct {
+	snippets  expiredSnippetDeleter
+	logger    *slog.Logger
+	interval  time.Duration
+	grace     time.Duration
+	batchSize int
+	now       func() time.Time
+}
+
+// validate checks the reaper's settings, which come straight from the command
+// line. A zero interval would make time.NewTicker() panic, a batch size below
+// one would make reap loop forever and a negative grace period would delete
+// snippets before they expire.
+func (rp *reaper) validate() error {
+	switch {
+	case rp.interval <= 0:
+		return errors.New("reaper: interval must be positive")
+	case rp.grace < 0:
+		return errors.New("reaper: grace period must not be negative")
+	case rp.batchSize < 1:
+		return errors.New("reaper: batch size must be at least 1")
+	}
+	return nil
+}
+
+// run purges expired snippets once every interval until ctx is cancelled. It
+// doesn't return until any purge in progress has finished, so once run has
+// returned the reaper is no longer touching the database. If the settings are
+// invalid it logs an error and returns straight away.
+func (rp *reaper) run(ctx context.Context) {
+	err := rp.validate()
+	if err != nil {
+		rp.logger.Error(err.Error())
+		return
+	}
+
+	ticker := time.NewTicker(rp.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			rp.reap(ctx)
+		}
+	}
+}
+
+// reap deletes every snippet which expired more than grace ago, one batch at
+// a time, and returns the number deleted. It stops early if ctx is cancelled
+// between batches.
+func (rp *reaper) reap(ctx context.Context) int {
+	if rp.batchSize < 1 || rp.grace < 0 {
+		rp.logger.Error("reaper: invalid batch size or grace period", "batch_size", rp.batchSize, "grace", rp.grace)
+		return 0
+	}
+
+	before := rp.now().Add(-rp.grace)
+	total := 0
+
+	for ctx.Err() == nil {
+		n, err := rp.snippets.DeleteExpired(before, rp.batchSize)
+		if err != nil {
+			rp.logger.Error(err.Error(), "deleted", total)
+			return total
+		}
+		total += n
+
+		// A short batch means that there's nothing left to delete.
+		if n < rp.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		rp.logger.Info("Purged expired snippets", "deleted", total, "expired_before", before)
+	}
+
+	return total
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+// fakeDeleter holds the expiry times of a set of snippets in memory and
+// records the arguments of every DeleteExpired() call.
+type fakeDeleter struct {
+	expires []time.Time
+	befores []time.Time
+	err     error
+}
+
+func (d *fakeDeleter) DeleteExpired(before time.Time, limit int) (int, error) {
+	d.befores = append(d.befores, before)
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	var kept []time.Time
+	deleted := 0
+	for _, expires := range d.expires {
+		if deleted < limit && expires.Before(before) {
+			deleted++
+			continue
+		}
+		kept = append(kept, expires)
+	}
+	d.expires = kept
+
+	return deleted, nil
+}
+
+func TestReaperReap(t *testing.T) {
+	now := time.Date(2025, 7, 5, 18, 48, 0, 0, time.UTC)
+
+	d := &fakeDeleter{}
+	for i := range 5 {
+		d.expires = append(d.expires, now.Add(-time.Duration(i+2)*time.Hour))
+	}
+	d.expires = append(d.expires, now.Add(-30*time.Minute), now.Add(time.Hour))
+
+	var logs bytes.Buffer
+
+	rp := &reaper{
+		snippets:  d,
+		logger:    slog.New(slog.NewTextHandler(&logs, nil)),
+		grace:     time.Hour,
+		batchSize: 2,
+		now:       func() time.Time { return now },
+	}
+
+	// The five snippets which expired more than an hour ago are deleted in
+	// three batches. The snippet within its grace period and the one which
+	// hasn't expired yet are kept.
+	n := rp.reap(context.Background())
+	assert.Equal(t, n, 5)
+	assert.Equal(t, len(d.expires), 2)
+	assert.Equal(t, len(d.befores), 3)
+	assert.Equal(t, d.befores[0], now.Add(-time.Hour))
+	assert.StringContains(t, logs.String(), "deleted=5")
+
+	// There's nothing left to delete, so nothing is logged.
+	logs.Reset()
+	n = rp.reap(context.Background())
+	assert.Equal(t, n, 0)
+	assert.Equal(t, logs.String(), "")
+}
+
+func TestReaperReapError(t *testing.T) {
+	d := &fakeDeleter{err: errors.New("database is down")}
+
+	var logs bytes.Buffer
+
+	rp := &reaper{
+		snippets:  d,
+		logger:    slog.New(slog.NewTextHandler(&logs, nil)),
+		batchSize: 2,
+		now:       time.Now,
+	}
+
+	n := rp.reap(context.Background())
+	assert.Equal(t, n, 0)
+	assert.StringContains(t, logs.String(), "level=ERROR")
+	assert.StringContains(t, logs.String(), "database is down")
+}
+
+func TestReaperRun(t *testing.T) {
+	d := &fakeDeleter{}
+
+	rp := &reaper{
+		snippets:  d,
+		logger:    slog.New(slog.DiscardHandler),
+		interval:  time.Millisecond,
+		batchSize: 2,
+		now:       time.Now,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rp.run(ctx)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("reaper didn't stop after its context was cancelled")
+	}
+}
+
+func TestReaperInvalidSettings(t *testing.T) {
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		grace     time.Duration
+		batchSize int
+		wantLog   string
+	}{
+		{
+			name:      "Zero interval",
+			batchSize: 2,
+			wantLog:   "interval must be positive",
+		},
+		{
+			name:      "Negative grace",
+			interval:  time.Millisecond,
+			grace:     -time.Hour,
+			batchSize: 2,
+			wantLog:   "grace period must not be negative",
+		},
+		{
+			name:     "Zero batch size",
+			interval: time.Millisecond,
+			wantLog:  "batch size must be at least 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDeleter{expires: []time.Time{time.Now().Add(-48 * time.Hour)}}
+
+			var logs bytes.Buffer
+
+			rp := &reaper{
+				snippets:  d,
+				logger:    slog.New(slog.NewTextHandler(&logs, nil)),
+				interval:  tt.interval,
+				grace:     tt.grace,
+				batchSize: tt.batchSize,
+				now:       time.Now,
+			}
+
+			// run returns at once rather than panicking or spinning, and
+			// nothing is deleted.
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				rp.run(context.Background())
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("reaper ran with invalid settings")
+			}
+
+			assert.Equal(t, len(d.befores), 0)
+			assert.StringContains(t, logs.String(), "level=ERROR")
+			assert.StringContains(t, logs.String(), tt.wantLog)
+		})
+	}
+
+	t.Run("Reap with zero batch size", func(t *testing.T) {
+		d := &fakeDeleter{expires: []time.Time{time.Now().Add(-48 * time.Hour)}}
+
+		rp := &reaper{
+			snippets: d,
+			logger:   slog.New(slog.DiscardHandler),
+			now:      time.Now,
+		}
+
+		assert.Equal(t, rp.reap(context.Background()), 0)
+		assert.Equal(t, len(d.befores), 0)
+	})
+}
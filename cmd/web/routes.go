@@ -34,7 +34,14 @@ func (app *application) routes() http.Handler {
 	dynamic := alice.New(app.sessionManager.LoadAndSave, noSurf, app.authenticate)
 
 	mux.Handle("GET /{$}", dynamic.ThenFunc(app.home))
-	mux.Handle("GET /snippet/view/{id}", dynamic.ThenFunc(app.snippetView))
+	mux.Handle("GET /snippet/view/{slug}", dynamic.ThenFunc(app.snippetView))
+	mux.Handle("POST /snippet/unlock/{slug}", dynamic.ThenFunc(app.snippetUnlockPost))
+	mux.Handle("GET /snippet/raw/{slug}", dynamic.ThenFunc(app.snippetRaw))
+	mux.Handle("GET /snippet/download/{slug}", dynamic.ThenFunc(app.snippetDownload))
+	mux.Handle("GET /snippet/history/{slug}", dynamic.ThenFunc(app.snippetHistory))
+	mux.Handle("GET /snippet/diff/{slug}", dynamic.ThenFunc(app.snippetDiff))
+	mux.Handle("GET /tag/{name}", dynamic.ThenFunc(app.tagView))
+	mux.Handle("GET /search", dynamic.ThenFunc(app.search))
 	mux.Handle("GET /user/signup", dynamic.ThenFunc(app.userSignup))
 	mux.Handle("POST /user/signup", dynamic.ThenFunc(app.userSignupPost))
 	mux.Handle("GET /user/login", dynamic.ThenFunc(app.userLogin))
@@ -46,8 +53,32 @@ func (app *application) routes() http.Handler {
 
 	mux.Handle("GET /snippet/create", protected.ThenFunc(app.snippetCreate))
 	mux.Handle("POST /snippet/create", protected.ThenFunc(app.snippetCreatePost))
+	mux.Handle("POST /snippet/fork/{slug}", protected.ThenFunc(app.snippetForkPost))
+	mux.Handle("GET /snippet/edit/{id}", protected.ThenFunc(app.snippetEdit))
+	mux.Handle("POST /snippet/edit/{id}", protected.ThenFunc(app.snippetEditPost))
+	mux.Handle("GET /snippet/expiry/{id}", protected.ThenFunc(app.snippetExpiry))
+	mux.Handle("POST /snippet/expiry/{id}", protected.ThenFunc(app.snippetExpiryPost))
+	mux.Handle("POST /snippet/restore/{id}", protected.ThenFunc(app.snippetRestorePost))
+	mux.Handle("POST /snippet/delete/{id}", protected.ThenFunc(app.snippetDeletePost))
+	mux.Handle("GET /user/snippets", protected.ThenFunc(app.userSnippets))
+	mux.Handle("GET /user/tokens", protected.ThenFunc(app.userTokens))
+	mux.Handle("POST /user/tokens", protected.ThenFunc(app.userTokensPost))
+	mux.Handle("POST /user/tokens/revoke/{id}", protected.ThenFunc(app.userTokenRevokePost))
 	mux.Handle("POST /user/logout", protected.ThenFunc(app.userLogoutPost))
 
+	// The JSON API has its own middleware chains, which report errors as JSON
+	// and accept API tokens as well as session cookies.
+	api := alice.New(app.sessionManager.LoadAndSave, app.apiAuthenticate)
+	apiProtected := api.Append(app.requireAPIAuthentication)
+
+	mux.Handle("/api/v1/", api.ThenFunc(app.apiNotFound))
+	mux.Handle("GET /api/v1/snippets", api.ThenFunc(app.apiSnippetList))
+	mux.Handle("GET /api/v1/snippets/{slug}", api.ThenFunc(app.apiSnippetGet))
+	mux.Handle("GET /api/v1/search", api.ThenFunc(app.apiSearch))
+	mux.Handle("POST /api/v1/snippets", apiProtected.ThenFunc(app.apiSnippetCreate))
+	mux.Handle("PUT /api/v1/snippets/{slug}", apiProtected.ThenFunc(app.apiSnippetUpdate))
+	mux.Handle("DELETE /api/v1/snippets/{slug}", apiProtected.ThenFunc(app.apiSnippetDelete))
+
 	//return app.recoverPanic(app.logRequest(commonHeaders(mux)))
 	// Create a middleware chain containing our 'standard' middleware
 	// which will be used for every request our application receives.
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// serve 运行 HTTP 服务器和过期片段清理任务，直到 ctx 被取消（在 main() 中是收到
+// SIGINT 或 SIGTERM 信号）。之后它停止接受新连接，最多等待 shutdownTimeout 让
+// 正在处理的请求完成，再停止清理任务。正常停止时返回 nil。
+//
+// listen starts the server, like srv.ListenAndServeTLS(), and is a parameter
+// so that tests can serve on a listener of their own.
+func (app *application) serve(ctx context.Context, srv *http.Server, listen func() error, rp *reaper, shutdownTimeout time.Duration) error {
+	// Start the background reaper which purges expired snippets. It's stopped
+	// however serve returns, and serve waits until it has stopped so that
+	// it's safe to close the database afterwards.
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	reaperDone := make(chan struct{})
+	go func() {
+		defer close(reaperDone)
+		rp.run(reaperCtx)
+	}()
+	defer func() {
+		stopReaper()
+		<-reaperDone
+		app.logger.Info("Stopped background workers")
+	}()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- listen()
+	}()
+
+	// If the server stops by itself, for example because the address is
+	// already in use, there's nothing to shut down.
+	select {
+	case err := <-serverErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	app.logger.Info("Shutting down server", "timeout", shutdownTimeout)
+
+	// Shutdown() closes the listener straight away, so no new connections
+	// are accepted, and then waits for the requests in progress to finish.
+	// If they don't finish in time, the remaining connections are closed
+	// forcibly.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	if err != nil {
+		srv.Close()
+		return err
+	}
+
+	// Once Shutdown() has been called, listen returns http.ErrServerClosed,
+	// which just means that the server stopped as it was asked to.
+	err = <-serverErr
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	app.logger.Info("Stopped server")
+
+	return nil
+}
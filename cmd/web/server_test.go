@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+// slowServer returns a server and listener for serve() whose /slow handler
+// closes started when a request arrives, and then waits until release is
+// closed before responding.
+func slowServer(t *testing.T, started, release chan struct{}) (*http.Server, net.Listener) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &http.Server{Handler: mux}, ln
+}
+
+// testReaper returns a reaper which runs every millisecond.
+func testReaper(d *fakeDeleter) *reaper {
+	return &reaper{
+		snippets:  d,
+		logger:    slog.New(slog.DiscardHandler),
+		interval:  time.Millisecond,
+		batchSize: 2,
+		now:       time.Now,
+	}
+}
+
+func TestServe(t *testing.T) {
+	app := newTestApplication(t)
+
+	t.Run("Graceful shutdown", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		srv, ln := slowServer(t, started, release)
+		d := &fakeDeleter{}
+
+		// Shut down on SIGTERM, in the same way as main(). While the signal is
+		// being relayed to ctx it doesn't stop the test process.
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+		defer stop()
+
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- app.serve(ctx, srv, func() error { return srv.Serve(ln) }, testReaper(d), 5*time.Second)
+		}()
+
+		// Start a request which is still in progress when the signal arrives.
+		type response struct {
+			body string
+			err  error
+		}
+		responses := make(chan response, 1)
+		go func() {
+			res, err := http.Get("http://" + ln.Addr().String() + "/slow")
+			if err != nil {
+				responses <- response{err: err}
+				return
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			responses <- response{body: string(body), err: err}
+		}()
+		<-started
+
+		p, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = p.Signal(syscall.SIGTERM)
+		if err != nil {
+			t.Skipf("can't send SIGTERM: %v", err)
+		}
+
+		// New connections are refused once the server has started shutting
+		// down...
+		deadline := time.Now().Add(time.Second)
+		for {
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				break
+			}
+			conn.Close()
+			if time.Now().After(deadline) {
+				t.Fatal("server still accepting connections after SIGTERM")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		// ...but serve() waits for the request in progress.
+		select {
+		case err := <-serveErr:
+			t.Fatalf("serve returned %v before the request finished", err)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+
+		res := <-responses
+		assert.NilError(t, res.err)
+		assert.Equal(t, res.body, "done")
+
+		assert.NilError(t, <-serveErr)
+
+		// The reaper has stopped too, so it makes no more calls.
+		calls := len(d.befores)
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, len(d.befores), calls)
+	})
+
+	t.Run("Shutdown timeout", func(t *testing.T) {
+		started, release := make(chan struct{}), make(chan struct{})
+		defer close(release)
+		srv, ln := slowServer(t, started, release)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- app.serve(ctx, srv, func() error { return srv.Serve(ln) }, testReaper(&fakeDeleter{}), 50*time.Millisecond)
+		}()
+
+		go http.Get("http://" + ln.Addr().String() + "/slow")
+		<-started
+
+		cancel()
+
+		err := <-serveErr
+		assert.Equal(t, errors.Is(err, context.DeadlineExceeded), true)
+	})
+
+	t.Run("Listen error", func(t *testing.T) {
+		listenErr := errors.New("address already in use")
+
+		err := app.serve(context.Background(), &http.Server{}, func() error { return listenErr }, testReaper(&fakeDeleter{}), time.Second)
+		assert.Equal(t, err, listenErr)
+	})
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+	"snippetbox.xmxxmx.us/internal/snippetctl"
+)
+
+// runSnippetctl runs the snippetctl command line client against the test
+// server, authenticated with the given API token, and returns its exit
+// status, stdout and stderr.
+func runSnippetctl(t *testing.T, ts *testServer, token, stdin string, args ...string) (int, string, string) {
+	config, err := json.Marshal(snippetctl.Config{URL: ts.URL, Token: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	err = os.WriteFile(configPath, config, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	status := snippetctl.Run(append([]string{"-config", configPath}, args...), snippetctl.Env{
+		Stdin:      strings.NewReader(stdin),
+		Stdout:     &stdout,
+		Stderr:     &stderr,
+		HTTPClient: ts.Client(),
+	})
+
+	return status, stdout.String(), stderr.String()
+}
+
+func TestSnippetctl(t *testing.T) {
+	app := newTestApplication(t)
+	ts := newTestServer(t, app.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		token      string
+		stdin      string
+		args       []string
+		wantStatus int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "Create",
+			token:      "sbx_aliceReadWrite",
+			stdin:      "#!/bin/sh\necho hello\n",
+			args:       []string{"create", "-t", "Hello script", "-tags", "bash, scripts"},
+			wantStdout: ts.URL + "/snippet/view/Hm4tE9wQz1B\n",
+		},
+		{
+			name:       "Create without a title",
+			token:      "sbx_aliceReadWrite",
+			args:       []string{"create"},
+			wantStatus: 2,
+			wantStderr: "the -t flag is required",
+		},
+		{
+			name:       "Create with a read only token",
+			token:      "sbx_bobReadOnly",
+			stdin:      "echo hello",
+			args:       []string{"create", "-t", "Hello script"},
+			wantStatus: 1,
+			wantStderr: "doesn't have the write scope (403)",
+		},
+		{
+			name:       "Create invalid snippet",
+			token:      "sbx_aliceReadWrite",
+			args:       []string{"create", "-t", "Empty", "-visibility", "everyone"},
+			wantStatus: 1,
+			wantStderr: "content: This field cannot be blank; visibility: This field must equal public, unlisted or private",
+		},
+		{
+			name:       "Get",
+			args:       []string{"get", "q7Yx2LpK0aZ"},
+			wantStdout: "Title:      An old silent pond\n",
+		},
+		{
+			name:       "Get as JSON",
+			args:       []string{"-json", "get", "q7Yx2LpK0aZ"},
+			wantStdout: `"content": "An old silent pond..."`,
+		},
+		{
+			name:       "Get someone else's private snippet",
+			token:      "sbx_bobReadOnly",
+			args:       []string{"get", "Vn3_cR8sWd-"},
+			wantStatus: 1,
+			wantStderr: "could not be found (404)",
+		},
+		{
+			name:       "Raw",
+			token:      "sbx_aliceReadWrite",
+			args:       []string{"raw", "Vn3_cR8sWd-"},
+			wantStdout: "A frog jumps in...",
+		},
+		{
+			name:       "List",
+			args:       []string{"list"},
+			wantStdout: "q7Yx2LpK0aZ  An old silent pond  " + ts.URL + "/snippet/view/q7Yx2LpK0aZ\n",
+		},
+		{
+			name:       "List as JSON",
+			args:       []string{"-json", "list", "-tag", "haiku"},
+			wantStdout: `"slug": "q7Yx2LpK0aZ"`,
+		},
+		{
+			name:       "Search",
+			args:       []string{"search", "silent", "pond"},
+			wantStdout: "q7Yx2LpK0aZ",
+		},
+		{
+			name:       "Search without terms",
+			args:       []string{"search"},
+			wantStatus: 2,
+			wantStderr: "missing search terms",
+		},
+		{
+			name:       "Delete",
+			token:      "sbx_aliceReadWrite",
+			args:       []string{"delete", "q7Yx2LpK0aZ"},
+			wantStdout: "Deleted q7Yx2LpK0aZ\n",
+		},
+		{
+			name:       "Delete without a token",
+			args:       []string{"delete", "q7Yx2LpK0aZ"},
+			wantStatus: 1,
+			wantStderr: "you must be authenticated to access this resource (401)",
+		},
+		{
+			name:       "Unknown command",
+			args:       []string{"edit"},
+			wantStatus: 2,
+			wantStderr: `unknown command "edit"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, stdout, stderr := runSnippetctl(t, ts, tt.token, tt.stdin, tt.args...)
+
+			assert.Equal(t, status, tt.wantStatus)
+			assert.StringContains(t, stdout, tt.wantStdout)
+			assert.StringContains(t, stderr, tt.wantStderr)
+		})
+	}
+}
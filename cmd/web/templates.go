@@ -4,8 +4,12 @@ import (
 	"html/template"
 	"io/fs"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"snippetbox.xmxxmx.us/internal/diff"
 	"snippetbox.xmxxmx.us/internal/models"
 	"snippetbox.xmxxmx.us/ui"
 )
@@ -16,13 +20,25 @@ import (
 // to it as the project progresses.
 // Add a CurrentYear field to the templateData struct.
 type templateData struct {
-	CurrentYear     int
-	Snippet         models.Snippet
-	Snippets        []models.Snippet
-	Form            any
-	Flash           string
-	IsAuthenticated bool
-	CSRFToken       string
+	CurrentYear         int
+	Snippet             models.Snippet
+	ForkSource          models.Snippet
+	Snippets            []models.Snippet
+	Form                any
+	Flashes             []flash
+	IsAuthenticated     bool
+	AuthenticatedUserID int
+	CSRFToken           string
+	Sort                string
+	NextPageURL         string
+	PrevPageURL         string
+	Tag                 string
+	Revisions           []models.Revision
+	FromRevision        models.Revision
+	ToRevision          models.Revision
+	Diff                []diff.Hunk
+	Tokens              []models.APIToken
+	NewToken            string
 }
 
 // Create a humanDate function which returns a nicely formatted string
@@ -37,11 +53,84 @@ func humanDate(t time.Time) string {
 	return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+// expiryDate is like humanDate, but describes the zero time as "Never",
+// because that's how a snippet which never expires is represented.
+func expiryDate(t time.Time) string {
+	if t.IsZero() {
+		return "Never"
+	}
+
+	return humanDate(t)
+}
+
+// excerptContext is the number of characters shown either side of the first
+// search match in an excerpt.
+const excerptContext = 60
+
+// Create an excerpt function which returns a short extract of text around the
+// first match of any of the words in query, with every match wrapped in <mark>
+// tags. The text itself is HTML-escaped, so the result is safe to return as
+// template.HTML.
+func excerpt(text, query string) template.HTML {
+	// Build a case-insensitive regular expression which matches any of the
+	// words in the query.
+	var terms []string
+	for _, term := range strings.Fields(query) {
+		terms = append(terms, regexp.QuoteMeta(term))
+	}
+
+	var rx *regexp.Regexp
+	if len(terms) > 0 {
+		rx = regexp.MustCompile("(?i)" + strings.Join(terms, "|"))
+	}
+
+	// Work out the window of characters to show, centred on the first match
+	// if there is one. We count in runes so that we never cut a multi-byte
+	// character in half.
+	runes := []rune(text)
+	start := 0
+	if rx != nil {
+		if loc := rx.FindStringIndex(text); loc != nil {
+			start = max(0, utf8.RuneCountInString(text[:loc[0]])-excerptContext)
+		}
+	}
+	end := min(len(runes), start+2*excerptContext)
+	window := string(runes[start:end])
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	last := 0
+	if rx != nil {
+		for _, loc := range rx.FindAllStringIndex(window, -1) {
+			b.WriteString(template.HTMLEscapeString(window[last:loc[0]]))
+			b.WriteString("<mark>")
+			b.WriteString(template.HTMLEscapeString(window[loc[0]:loc[1]]))
+			b.WriteString("</mark>")
+			last = loc[1]
+		}
+	}
+	b.WriteString(template.HTMLEscapeString(window[last:]))
+
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return template.HTML(b.String())
+}
+
 // Initialize a template.FuncMap value and store it in a global variable. This is
 // essentially a string-keyed map which acts as a lookup table mapping names to
 // functions.
 var functions = template.FuncMap{
-	"humanDate": humanDate,
+	"humanDate":    humanDate,
+	"expiryDate":   expiryDate,
+	"excerpt":      excerpt,
+	"highlight":    highlight,
+	"languageName": languageName,
+	"languages":    func() []language { return languages },
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"html/template"
+	"strings"
 	"testing"
 	"time"
 
@@ -51,3 +53,116 @@ func TestHumanDate(t *testing.T) {
 		})
 	}
 }
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("a", 100) + " nginx " + strings.Repeat("b", 100)
+
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  template.HTML
+	}{
+		{
+			name:  "Single match",
+			text:  "server { listen 80; }",
+			query: "listen",
+			want:  "server { <mark>listen</mark> 80; }",
+		},
+		{
+			name:  "Multiple terms and case",
+			text:  "Nginx reverse proxy for nginx",
+			query: "NGINX proxy",
+			want:  "<mark>Nginx</mark> reverse <mark>proxy</mark> for <mark>nginx</mark>",
+		},
+		{
+			name:  "Escapes HTML",
+			text:  "<script>alert(1)</script>",
+			query: "alert",
+			want:  "&lt;script&gt;<mark>alert</mark>(1)&lt;/script&gt;",
+		},
+		{
+			name:  "Truncates around match",
+			text:  long,
+			query: "nginx",
+			want:  template.HTML("…" + strings.Repeat("a", 59) + " <mark>nginx</mark> " + strings.Repeat("b", 54) + "…"),
+		},
+		{
+			name:  "Empty query",
+			text:  "An old silent pond",
+			query: "",
+			want:  "An old silent pond",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, excerpt(tt.text, tt.query), tt.want)
+		})
+	}
+}
+
+func TestExpiryDate(t *testing.T) {
+	assert.Equal(t, expiryDate(time.Time{}), "Never")
+	assert.Equal(t, expiryDate(time.Date(2025, 7, 5, 18, 48, 0, 0, time.UTC)), "05 Jul 2025 at 18:48")
+}
+
+func TestBaseFlashes(t *testing.T) {
+	templateCache, err := newTemplateCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		flashes []flash
+		want    []string
+	}{
+		{
+			name: "Success",
+			flashes: []flash{
+				{Level: flashSuccess, Message: "Snippet successfully created!"},
+			},
+			want: []string{"<div class='flash flash-success'>Snippet successfully created!</div>"},
+		},
+		{
+			name: "Several levels",
+			flashes: []flash{
+				{Level: flashWarning, Message: "Careful"},
+				{Level: flashError, Message: "Something went wrong"},
+			},
+			want: []string{
+				"<div class='flash flash-warning'>Careful</div>",
+				"<div class='flash flash-error'>Something went wrong</div>",
+			},
+		},
+		{
+			name: "Escaped",
+			flashes: []flash{
+				{Level: flashSuccess, Message: "<b>bold</b>"},
+			},
+			want: []string{"<div class='flash flash-success'>&lt;b&gt;bold&lt;/b&gt;</div>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf strings.Builder
+
+			err := templateCache["burned.tmpl"].ExecuteTemplate(&buf, "base", templateData{Flashes: tt.flashes})
+			assert.NilError(t, err)
+
+			for _, want := range tt.want {
+				assert.StringContains(t, buf.String(), want)
+			}
+		})
+	}
+
+	t.Run("No flashes", func(t *testing.T) {
+		var buf strings.Builder
+
+		err := templateCache["burned.tmpl"].ExecuteTemplate(&buf, "base", templateData{})
+		assert.NilError(t, err)
+		assert.Equal(t, strings.Contains(buf.String(), "class='flash"), false)
+	})
+}
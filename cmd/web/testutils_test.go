@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -62,9 +63,29 @@ func newTestApplication(t *testing.T) *application {
 		formDecoder:    formDecoder,
 		sessionManager: sessionManager,
 		users:          &mocks.UserModel{},
+		tokens:         &mocks.TokenModel{},
 	}
 }
 
+// Create a withTestLogin helper which wraps our application routes with an
+// extra GET /test/login/{id} route. Requesting this route stores the user ID
+// in the session in the same way that userLoginPost does, which lets tests
+// authenticate the test server client as any user without going through the
+// login form.
+func withTestLogin(app *application) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", app.routes())
+	mux.Handle("GET /test/login/{id}", app.sessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		app.sessionManager.Put(r.Context(), "authenticatedUserID", id)
+	})))
+	return mux
+}
+
 // Define a custom testServer type which embeds an httptest.Server instance.
 type testServer struct {
 	*httptest.Server
@@ -144,3 +165,32 @@ func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (i
 
 	return res.StatusCode, res.Header, string(body)
 }
+
+// request sends a request with the given method, body and extra headers to the
+// test server, and returns the response status code, headers and body. It's
+// used for the JSON API, which needs methods and headers that get() and
+// postForm() don't support.
+func (ts *testServer) request(t *testing.T, method, urlPath, body string, header http.Header) (int, http.Header, string) {
+	req, err := http.NewRequest(method, ts.URL+urlPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	res, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resBody = bytes.TrimSpace(resBody)
+
+	return res.StatusCode, res.Header, string(resBody)
+}
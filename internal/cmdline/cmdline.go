@@ -0,0 +1,53 @@
+// Package cmdline 提供 admin 和 snippetctl 两个命令行工具共用的子命令参数解析
+// 和退出状态。
+package cmdline
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// UsageError is returned by a subcommand whose arguments are wrong.
+type UsageError string
+
+func (e UsageError) Error() string {
+	return string(e)
+}
+
+// FlagSet returns a new flag set for a subcommand, which writes its errors to
+// stderr. The name includes the program's, like "admin create-user".
+func FlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// Parse parses a subcommand's arguments, returning a UsageError if they are
+// wrong or there are more than maxArgs left over after the flags.
+func Parse(fs *flag.FlagSet, args []string, maxArgs int) error {
+	err := fs.Parse(args)
+	if err != nil {
+		return UsageError(err.Error())
+	}
+	if fs.NArg() > maxArgs {
+		return UsageError(fmt.Sprintf("unexpected argument %q", fs.Arg(maxArgs)))
+	}
+	return nil
+}
+
+// ExitStatus returns the exit status for the error returned by a subcommand.
+// A UsageError gives status 2, the same as the flag package uses for bad
+// flags, and any other error gives status 1.
+func ExitStatus(err error) int {
+	var usageErr UsageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usageErr):
+		return 2
+	default:
+		return 1
+	}
+}
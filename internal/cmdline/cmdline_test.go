@@ -0,0 +1,66 @@
+package cmdline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		maxArgs    int
+		wantErr    string
+		wantStatus int
+	}{
+		{
+			name:    "Flags only",
+			args:    []string{"-email", "alice@example.com"},
+			maxArgs: 0,
+		},
+		{
+			name:    "Allowed argument",
+			args:    []string{"-email", "alice@example.com", "q7Yx2LpK0aZ"},
+			maxArgs: 1,
+		},
+		{
+			name:       "Extra argument",
+			args:       []string{"-email", "alice@example.com", "q7Yx2LpK0aZ"},
+			maxArgs:    0,
+			wantErr:    `unexpected argument "q7Yx2LpK0aZ"`,
+			wantStatus: 2,
+		},
+		{
+			name:       "Unknown flag",
+			args:       []string{"-name", "Alice"},
+			wantErr:    "flag provided but not defined: -name",
+			wantStatus: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := FlagSet("admin test", io.Discard)
+			fs.String("email", "", "Email address")
+
+			err := Parse(fs, tt.args, tt.maxArgs)
+
+			if tt.wantErr == "" {
+				assert.NilError(t, err)
+			} else {
+				assert.Equal(t, fmt.Sprint(err), tt.wantErr)
+			}
+			assert.Equal(t, ExitStatus(err), tt.wantStatus)
+		})
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	// A usage error is still recognised once it has been wrapped.
+	assert.Equal(t, ExitStatus(errors.New("connection refused")), 1)
+	assert.Equal(t, ExitStatus(fmt.Errorf("reading input: %w", UsageError("missing slug"))), 2)
+}
@@ -0,0 +1,321 @@
+// Package config 加载 snippetbox 的配置。每个设置依次取默认值、JSON 配置文件、
+// SNIPPETBOX_* 环境变量和命令行参数，后面的来源覆盖前面的来源。
+//
+// A setting has the same name everywhere, spelled to suit each source: the
+// reaper-interval flag is the SNIPPETBOX_REAPER_INTERVAL environment variable
+// and the "reaper_interval" key in the config file.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config 保存 web 应用的全部设置。DBBackend 是数据库后端，mysql 或 sqlite；
+// DSN 是密钥，打印时会隐去其中的密码，使用 SQLite 时它是数据库文件的路径；
+// DSNFile 是保存 DSN 的文件路径，适合配合 Docker 或 Kubernetes 的 secret 使用。
+type Config struct {
+	Addr            string
+	DBBackend       string
+	DSN             string
+	DSNFile         string
+	TLSCert         string
+	TLSKey          string
+	SessionLifetime time.Duration
+	IdleTimeout     time.Duration
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	ShutdownTimeout time.Duration
+	ReaperInterval  time.Duration
+	ReaperGrace     time.Duration
+	ReaperBatch     int
+}
+
+// Default 返回默认配置。DSN 没有默认值，必须由配置文件、环境变量或命令行参数提供
+func Default() Config {
+	return Config{
+		Addr:            ":4000",
+		DBBackend:       "mysql",
+		TLSCert:         "./tls/cert.pem",
+		TLSKey:          "./tls/key.pem",
+		SessionLifetime: 12 * time.Hour,
+		IdleTimeout:     time.Minute,
+		ReadTimeout:     5 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+		ReaperInterval:  10 * time.Minute,
+		ReaperGrace:     24 * time.Hour,
+		ReaperBatch:     500,
+	}
+}
+
+// define registers a flag for each setting of c on fs, in the order that
+// they're listed in the usage message, and returns fs. The flags write
+// straight into c.
+func (c *Config) define(fs *flag.FlagSet) *flag.FlagSet {
+	fs.StringVar(&c.Addr, "addr", c.Addr, "HTTP network address")
+	fs.StringVar(&c.DBBackend, "db-backend", c.DBBackend, "Database backend: mysql or sqlite")
+	fs.StringVar(&c.DSN, "dsn", c.DSN, "MySQL data source name, or the path of the SQLite database file")
+	fs.StringVar(&c.DSNFile, "dsn-file", c.DSNFile, "File to read the data source name from, instead of -dsn")
+	fs.StringVar(&c.TLSCert, "tls-cert", c.TLSCert, "TLS certificate file")
+	fs.StringVar(&c.TLSKey, "tls-key", c.TLSKey, "TLS private key file")
+	fs.DurationVar(&c.SessionLifetime, "session-lifetime", c.SessionLifetime, "How long sessions last after they're created")
+	fs.DurationVar(&c.IdleTimeout, "idle-timeout", c.IdleTimeout, "How long to keep idle keep-alive connections open")
+	fs.DurationVar(&c.ReadTimeout, "read-timeout", c.ReadTimeout, "Maximum time to read a request")
+	fs.DurationVar(&c.WriteTimeout, "write-timeout", c.WriteTimeout, "Maximum time to write a response")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "How long to wait for requests in progress when shutting down")
+	fs.DurationVar(&c.ReaperInterval, "reaper-interval", c.ReaperInterval, "How often to purge expired snippets")
+	fs.DurationVar(&c.ReaperGrace, "reaper-grace", c.ReaperGrace, "How long to keep snippets after they expire")
+	fs.IntVar(&c.ReaperBatch, "reaper-batch", c.ReaperBatch, "Maximum number of expired snippets to delete at once")
+	return fs
+}
+
+// envVar returns the name of the environment variable for a setting.
+func envVar(name string) string {
+	return "SNIPPETBOX_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// fileKey returns the config file key for a setting.
+func fileKey(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// Load 在 fs 上注册 -config 参数和 names 中的设置（names 为空时注册全部设置），
+// 解析 args，然后依次应用默认值、配置文件、环境变量和命令行参数。配置文件的路径
+// 来自 -config 参数或 SNIPPETBOX_CONFIG 环境变量。Load 不检查设置的值，调用者
+// 应该接着调用 Validate。
+//
+// Any other flags which the caller has defined on fs are parsed as well, so
+// they can be read once Load has returned. getenv is normally os.Getenv.
+func Load(fs *flag.FlagSet, args []string, getenv func(string) string, names ...string) (Config, error) {
+	cfg := Default()
+	settings := cfg.define(flag.NewFlagSet("config", flag.ContinueOnError))
+
+	// The flags are parsed into a scratch Config, so that it's possible to
+	// tell which ones were set and apply them after the config file and the
+	// environment.
+	selected := map[string]bool{}
+	scratch := Default()
+	scratch.define(flag.NewFlagSet("flags", flag.ContinueOnError)).VisitAll(func(f *flag.Flag) {
+		if len(names) == 0 || slices.Contains(names, f.Name) {
+			selected[f.Name] = true
+			fs.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	for _, name := range names {
+		if settings.Lookup(name) == nil {
+			panic("config: unknown setting " + name)
+		}
+	}
+
+	configPath := fs.String("config", "", "JSON config file (or set SNIPPETBOX_CONFIG)")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return Config{}, err
+	}
+
+	path := *configPath
+	if path == "" {
+		path = getenv("SNIPPETBOX_CONFIG")
+	}
+	if path != "" {
+		err = loadFile(settings, selected, path)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	settings.VisitAll(func(f *flag.Flag) {
+		value := getenv(envVar(f.Name))
+		if err != nil || value == "" || !selected[f.Name] {
+			return
+		}
+		if settings.Set(f.Name, value) != nil {
+			err = fmt.Errorf("environment variable %s: invalid value %q", envVar(f.Name), value)
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if err == nil && selected[f.Name] {
+			err = settings.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+
+	err = cfg.readSecrets()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+// loadFile applies the settings in the JSON config file at path. Keys for
+// settings which weren't selected are ignored, so that a file can be shared
+// by commands which use different settings, but unknown keys are an error
+// because they're most likely typos.
+func loadFile(settings *flag.FlagSet, selected map[string]bool, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	var values map[string]any
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	err = dec.Decode(&values)
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	// The keys are applied in order, so that the same error is reported for
+	// a file every time.
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		v := values[key]
+		name := strings.ReplaceAll(key, "_", "-")
+		if settings.Lookup(name) == nil || fileKey(name) != key {
+			return fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+		if !selected[name] {
+			continue
+		}
+
+		var value string
+		switch v := v.(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		default:
+			return fmt.Errorf("config file %s: %q must be a string or a number", path, key)
+		}
+
+		if settings.Set(name, value) != nil {
+			return fmt.Errorf("config file %s: invalid value %q for %q", path, value, key)
+		}
+	}
+
+	return nil
+}
+
+// readSecrets reads the settings which can be given as files.
+func (c *Config) readSecrets() error {
+	if c.DSNFile == "" {
+		return nil
+	}
+	if c.DSN != "" {
+		return errors.New("only one of dsn and dsn-file can be set")
+	}
+
+	b, err := os.ReadFile(c.DSNFile)
+	if err != nil {
+		return fmt.Errorf("dsn-file: %w", err)
+	}
+
+	// Files written by editors and `echo` usually end with a newline, which
+	// isn't part of the secret.
+	c.DSN = strings.TrimSpace(string(b))
+
+	return nil
+}
+
+// Validate 检查 names 中的设置（names 为空时检查全部设置），返回的错误列出
+// 每个无效的设置
+func (c Config) Validate(names ...string) error {
+	checks := []struct {
+		name string
+		ok   bool
+		msg  string
+	}{
+		{"addr", validAddr(c.Addr), "must be a host:port address, like :4000"},
+		{"db-backend", c.DBBackend == "mysql" || c.DBBackend == "sqlite", "must be mysql or sqlite"},
+		{"dsn", c.DSN != "", "must be set, with -dsn, -dsn-file, SNIPPETBOX_DSN, SNIPPETBOX_DSN_FILE or the config file"},
+		{"tls-cert", fileExists(c.TLSCert), fmt.Sprintf("file %q doesn't exist", c.TLSCert)},
+		{"tls-key", fileExists(c.TLSKey), fmt.Sprintf("file %q doesn't exist", c.TLSKey)},
+		{"session-lifetime", c.SessionLifetime > 0, "must be positive"},
+		{"idle-timeout", c.IdleTimeout > 0, "must be positive"},
+		{"read-timeout", c.ReadTimeout > 0, "must be positive"},
+		{"write-timeout", c.WriteTimeout > 0, "must be positive"},
+		{"shutdown-timeout", c.ShutdownTimeout > 0, "must be positive"},
+		{"reaper-interval", c.ReaperInterval > 0, "must be positive"},
+		{"reaper-grace", c.ReaperGrace >= 0, "must not be negative"},
+		{"reaper-batch", c.ReaperBatch > 0, "must be at least 1"},
+	}
+
+	var errs []error
+	for _, check := range checks {
+		if !check.ok && (len(names) == 0 || slices.Contains(names, check.name)) {
+			errs = append(errs, fmt.Errorf("%s %s", check.name, check.msg))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validAddr reports whether addr is a host:port address, where the host may
+// be empty to listen on all interfaces.
+func validAddr(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	return err == nil && port != ""
+}
+
+// fileExists reports whether path is a file which exists.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// dsnPasswordRX matches the password in a DSN like user:password@tcp(host)/db.
+// It runs up to the last @, because the password itself may contain one.
+var dsnPasswordRX = regexp.MustCompile(`^([^:@/]*):.*@`)
+
+// Print 以配置文件的 JSON 格式输出配置，DSN 中的密码被替换为 REDACTED，
+// 所以输出可以安全地贴到问题报告中
+func (c Config) Print(w io.Writer) error {
+	values := map[string]any{}
+
+	// A DSN which was read from a file is left out altogether, so that the
+	// output can be used as a config file without setting both.
+	cfg := c
+	cfg.DSN = dsnPasswordRX.ReplaceAllString(cfg.DSN, "$1:REDACTED@")
+	if cfg.DSNFile != "" {
+		cfg.DSN = ""
+	}
+	cfg.define(flag.NewFlagSet("print", flag.ContinueOnError)).VisitAll(func(f *flag.Flag) {
+		switch v := f.Value.(flag.Getter).Get().(type) {
+		case time.Duration:
+			values[fileKey(f.Name)] = v.String()
+		default:
+			values[fileKey(f.Name)] = v
+		}
+	})
+
+	b, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
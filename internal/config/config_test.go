@@ -0,0 +1,297 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+// writeFile writes content to a new file in a temporary directory and returns
+// its path.
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(content), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// load calls Load with a new flag set and the given environment.
+func load(args []string, env map[string]string, names ...string) (Config, error) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, args, func(key string) string { return env[key] }, names...)
+}
+
+func TestLoad(t *testing.T) {
+	configFile := writeFile(t, "config.json", `{
+		"addr": ":5000",
+		"dsn": "web:secret@/snippetbox?parseTime=true",
+		"reaper_interval": "1h",
+		"reaper_batch": 100
+	}`)
+	dsnFile := writeFile(t, "dsn", "web:filesecret@/snippetbox?parseTime=true\n")
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		check func(t *testing.T, cfg Config)
+	}{
+		{
+			name: "Defaults",
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, cfg, Default())
+			},
+		},
+		{
+			name: "Config file",
+			args: []string{"-config", configFile},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, cfg.Addr, ":5000")
+				assert.Equal(t, cfg.DSN, "web:secret@/snippetbox?parseTime=true")
+				assert.Equal(t, cfg.ReaperInterval, time.Hour)
+				assert.Equal(t, cfg.ReaperBatch, 100)
+				assert.Equal(t, cfg.ReaperGrace, 24*time.Hour)
+			},
+		},
+		{
+			name: "Config file from the environment",
+			env:  map[string]string{"SNIPPETBOX_CONFIG": configFile},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, cfg.Addr, ":5000")
+			},
+		},
+		{
+			name: "Environment overrides file",
+			args: []string{"-config", configFile},
+			env: map[string]string{
+				"SNIPPETBOX_ADDR":         ":6000",
+				"SNIPPETBOX_REAPER_BATCH": "50",
+			},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, cfg.Addr, ":6000")
+				assert.Equal(t, cfg.ReaperBatch, 50)
+				assert.Equal(t, cfg.ReaperInterval, time.Hour)
+			},
+		},
+		{
+			name: "Flags override environment",
+			args: []string{"-config", configFile, "-addr", ":7000", "-reaper-interval", "5m"},
+			env:  map[string]string{"SNIPPETBOX_ADDR": ":6000"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, cfg.Addr, ":7000")
+				assert.Equal(t, cfg.ReaperInterval, 5*time.Minute)
+			},
+		},
+		{
+			name: "Flag set to its default",
+			args: []string{"-addr", ":4000"},
+			env:  map[string]string{"SNIPPETBOX_ADDR": ":6000"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, cfg.Addr, ":4000")
+			},
+		},
+		{
+			name: "SQLite",
+			args: []string{"-db-backend", "sqlite", "-dsn", "./snippetbox.db"},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, cfg.DBBackend, "sqlite")
+				assert.Equal(t, cfg.DSN, "./snippetbox.db")
+			},
+		},
+		{
+			name: "DSN file",
+			env:  map[string]string{"SNIPPETBOX_DSN_FILE": dsnFile},
+			check: func(t *testing.T, cfg Config) {
+				assert.Equal(t, cfg.DSN, "web:filesecret@/snippetbox?parseTime=true")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := load(tt.args, tt.env)
+			assert.NilError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dsnFile := writeFile(t, "dsn", "web:filesecret@/snippetbox")
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "Unknown file setting",
+			args:    []string{"-config", writeFile(t, "config.json", `{"adr": ":5000"}`)},
+			wantErr: `unknown setting "adr"`,
+		},
+		{
+			name:    "Invalid file value",
+			args:    []string{"-config", writeFile(t, "config.json", `{"reaper_interval": "often"}`)},
+			wantErr: `invalid value "often" for "reaper_interval"`,
+		},
+		{
+			name:    "Invalid JSON",
+			args:    []string{"-config", writeFile(t, "config.json", `{"addr": }`)},
+			wantErr: "invalid character",
+		},
+		{
+			name:    "Missing file",
+			args:    []string{"-config", filepath.Join(t.TempDir(), "missing.json")},
+			wantErr: "no such file or directory",
+		},
+		{
+			name:    "Invalid environment value",
+			env:     map[string]string{"SNIPPETBOX_REAPER_BATCH": "lots"},
+			wantErr: `environment variable SNIPPETBOX_REAPER_BATCH: invalid value "lots"`,
+		},
+		{
+			name:    "Invalid flag",
+			args:    []string{"-session-lifetime", "forever"},
+			wantErr: `invalid value "forever" for flag -session-lifetime`,
+		},
+		{
+			name:    "DSN and DSN file",
+			args:    []string{"-dsn", "web:pass@/snippetbox", "-dsn-file", dsnFile},
+			wantErr: "only one of dsn and dsn-file can be set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(tt.args, tt.env)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			assert.StringContains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestLoadNames(t *testing.T) {
+	// A file with settings for the web application can be shared with a
+	// command which only uses some of them.
+	configFile := writeFile(t, "config.json", `{"addr": ":5000", "dsn": "web:pass@/snippetbox"}`)
+
+	cfg, err := load([]string{"-config", configFile}, nil, "dsn", "dsn-file")
+	assert.NilError(t, err)
+	assert.Equal(t, cfg.DSN, "web:pass@/snippetbox")
+	assert.Equal(t, cfg.Addr, ":4000")
+
+	// And only those settings have flags.
+	_, err = load([]string{"-addr", ":5000"}, nil, "dsn", "dsn-file")
+	assert.StringContains(t, err.Error(), "flag provided but not defined: -addr")
+}
+
+func TestValidate(t *testing.T) {
+	certFile := writeFile(t, "cert.pem", "cert")
+	keyFile := writeFile(t, "key.pem", "key")
+
+	valid := Default()
+	valid.DSN = "web:pass@/snippetbox"
+	valid.TLSCert = certFile
+	valid.TLSKey = keyFile
+
+	assert.NilError(t, valid.Validate())
+
+	invalid := valid
+	invalid.Addr = "4000"
+	invalid.DBBackend = "postgres"
+	invalid.DSN = ""
+	invalid.TLSKey = filepath.Join(t.TempDir(), "missing.pem")
+	invalid.ReaperBatch = 0
+	invalid.ReadTimeout = -time.Second
+
+	err := invalid.Validate()
+	if err == nil {
+		t.Fatal("got no error")
+	}
+
+	// Every invalid setting is listed, one per line.
+	lines := strings.Split(err.Error(), "\n")
+	assert.Equal(t, len(lines), 6)
+	assert.StringContains(t, lines[0], "addr must be a host:port address")
+	assert.StringContains(t, lines[1], "db-backend must be mysql or sqlite")
+	assert.StringContains(t, lines[2], "dsn must be set")
+	assert.StringContains(t, lines[3], "tls-key file")
+	assert.StringContains(t, lines[4], "read-timeout must be positive")
+	assert.StringContains(t, lines[5], "reaper-batch must be at least 1")
+
+	// Only the named settings are checked.
+	err = invalid.Validate("tls-cert", "session-lifetime")
+	assert.NilError(t, err)
+}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsn     string
+		dsnFile string
+		want    string
+	}{
+		{
+			name: "Password",
+			dsn:  "web:Mz8nQ3vR@/snippetbox?parseTime=true",
+			want: `"dsn": "web:REDACTED@/snippetbox?parseTime=true"`,
+		},
+		{
+			name: "Password containing @",
+			dsn:  "web:p@ss@tcp(db:3306)/snippetbox",
+			want: `"dsn": "web:REDACTED@tcp(db:3306)/snippetbox"`,
+		},
+		{
+			name: "No password",
+			dsn:  "web@/snippetbox",
+			want: `"dsn": "web@/snippetbox"`,
+		},
+		{
+			name:    "DSN from a file",
+			dsn:     "web:Mz8nQ3vR@/snippetbox",
+			dsnFile: "/run/secrets/dsn",
+			want:    `"dsn": ""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.DSN = tt.dsn
+			cfg.DSNFile = tt.dsnFile
+
+			var buf strings.Builder
+			err := cfg.Print(&buf)
+			assert.NilError(t, err)
+
+			assert.StringContains(t, buf.String(), tt.want)
+			assert.StringContains(t, buf.String(), `"reaper_interval": "10m0s"`)
+			assert.StringContains(t, buf.String(), `"reaper_batch": 500`)
+			assert.Equal(t, strings.Contains(buf.String(), "Mz8nQ3vR"), false)
+		})
+	}
+
+	// The output can be loaded again as a config file.
+	cfg := Default()
+	cfg.DSN = "web@/snippetbox"
+
+	var buf strings.Builder
+	err := cfg.Print(&buf)
+	assert.NilError(t, err)
+
+	loaded, err := load([]string{"-config", writeFile(t, "config.json", buf.String())}, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, loaded, cfg)
+}
@@ -0,0 +1,238 @@
+// Package diff 实现基于行的文本比较，并生成统一格式（unified diff）的差异。
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Kind 表示差异中一行的类型，它的值就是该行在统一格式中的前缀
+type Kind string
+
+const (
+	Equal  Kind = " "
+	Delete Kind = "-"
+	Insert Kind = "+"
+)
+
+// Line 是差异中的一行
+type Line struct {
+	Kind Kind
+	Text string
+}
+
+// Hunk 是差异中连续的一段修改及其上下文。FromLine 和 ToLine 是这一段在旧文本
+// 和新文本中的起始行号（从 1 开始），FromCount 和 ToCount 是它包含的行数。
+type Hunk struct {
+	FromLine  int
+	FromCount int
+	ToLine    int
+	ToCount   int
+	Lines     []Line
+}
+
+// Header returns the hunk's "@@ -l,s +l,s @@" header line.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.FromLine, h.FromCount), hunkRange(h.ToLine, h.ToCount))
+}
+
+// hunkRange formats one side of a hunk header. As in GNU diff, an empty range
+// refers to the line before it, and a count of one is left out.
+func hunkRange(line, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", line-1)
+	case 1:
+		return fmt.Sprint(line)
+	default:
+		return fmt.Sprintf("%d,%d", line, count)
+	}
+}
+
+// maxCells limits the work done to find the longest common subsequence, which
+// is proportional to the product of the two texts' lengths, so that comparing
+// two huge texts can't tie up the CPU. Beyond it the texts are treated as
+// entirely different.
+const maxCells = 10_000_000
+
+// Lines compares a and b line by line and returns the edit script which turns
+// a into b, with the fewest possible inserted and deleted lines.
+func Lines(a, b []string) []Line {
+	// Lines which are the same at the start and end of both texts are always
+	// part of the result, so we trim them off before doing the expensive part.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []Line
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Equal, text})
+	}
+
+	from, to := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(from)*len(to) > maxCells {
+		lines = append(lines, replace(from, to)...)
+	} else {
+		lines = append(lines, lcs(from, to)...)
+	}
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Equal, text})
+	}
+
+	return lines
+}
+
+// replace returns the edit script which deletes all of a and inserts all of b.
+func replace(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, text := range a {
+		lines = append(lines, Line{Delete, text})
+	}
+	for _, text := range b {
+		lines = append(lines, Line{Insert, text})
+	}
+	return lines
+}
+
+// lcs builds the edit script between a and b from their longest common
+// subsequence, using Hirschberg's algorithm. Rather than a table of every
+// pair of lines, it only keeps two rows of subsequence lengths at a time, so
+// it needs memory in proportion to the length of the texts, not their product.
+func lcs(a, b []string) []Line {
+	switch {
+	case len(a) == 0 || len(b) == 0:
+		return replace(a, b)
+	case len(a) == 1:
+		for j, text := range b {
+			if text == a[0] {
+				lines := replace(nil, b[:j])
+				lines = append(lines, Line{Equal, text})
+				return append(lines, replace(nil, b[j+1:])...)
+			}
+		}
+		return replace(a, b)
+	}
+
+	// Split a in half, and find the point to split b at so that the common
+	// subsequences of the two halves add up to the longest one overall. Each
+	// half can then be solved on its own.
+	mid := len(a) / 2
+	forward := lcsLengths(a[:mid], b, false)
+	backward := lcsLengths(a[mid:], b, true)
+
+	split, best := 0, -1
+	for j := range forward {
+		if n := forward[j] + backward[j]; n > best {
+			split, best = j, n
+		}
+	}
+
+	return append(lcs(a[:mid], b[:split]), lcs(a[mid:], b[split:])...)
+}
+
+// lcsLengths returns the lengths of the longest common subsequences of a and
+// each prefix of b, so that the j'th length is for b[:j]. If reverse is true
+// it compares the suffixes instead, so that the j'th length is for b[j:].
+func lcsLengths(a, b []string, reverse bool) []int {
+	// at returns the i'th line of s, counting from the end when reversed.
+	at := func(s []string, i int) string {
+		if reverse {
+			return s[len(s)-1-i]
+		}
+		return s[i]
+	}
+
+	prev := make([]int, len(b)+1)
+	row := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if at(a, i) == at(b, j) {
+				row[j+1] = prev[j] + 1
+			} else {
+				row[j+1] = max(prev[j+1], row[j])
+			}
+		}
+		prev, row = row, prev
+	}
+
+	if reverse {
+		slices.Reverse(prev)
+	}
+	return prev
+}
+
+// Hunks compares the texts a and b and groups the changes into hunks, each
+// with up to context unchanged lines either side. It returns nil if the texts
+// are the same.
+func Hunks(a, b string, context int) []Hunk {
+	lines := Lines(splitLines(a), splitLines(b))
+
+	var hunks []Hunk
+	var h *Hunk
+	fromLine, toLine := 1, 1
+
+	for i, line := range lines {
+		if line.Kind == Equal {
+			// Only keep an unchanged line if there is a change within
+			// context lines of it.
+			near := false
+			for k := max(0, i-context); k <= min(len(lines)-1, i+context); k++ {
+				if lines[k].Kind != Equal {
+					near = true
+					break
+				}
+			}
+			if !near {
+				if h != nil {
+					hunks = append(hunks, *h)
+					h = nil
+				}
+				fromLine++
+				toLine++
+				continue
+			}
+		}
+
+		if h == nil {
+			h = &Hunk{FromLine: fromLine, ToLine: toLine}
+		}
+		h.Lines = append(h.Lines, line)
+
+		switch line.Kind {
+		case Equal:
+			h.FromCount++
+			h.ToCount++
+			fromLine++
+			toLine++
+		case Delete:
+			h.FromCount++
+			fromLine++
+		case Insert:
+			h.ToCount++
+			toLine++
+		}
+	}
+	if h != nil {
+		hunks = append(hunks, *h)
+	}
+
+	return hunks
+}
+
+// splitLines splits text into lines, ignoring a final newline and treating
+// Windows line endings the same as Unix ones.
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,149 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+// unified formats hunks like the body of a unified diff.
+func unified(hunks []Hunk) string {
+	var sb strings.Builder
+	for _, h := range hunks {
+		sb.WriteString(h.Header() + "\n")
+		for _, line := range h.Lines {
+			sb.WriteString(string(line.Kind) + line.Text + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func TestHunksFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "Same",
+			a:    "one\ntwo\n",
+			b:    "one\ntwo",
+			want: "",
+		},
+		{
+			name: "Changed line",
+			a:    "one\ntwo\nthree\n",
+			b:    "one\n2\nthree\n",
+			want: "@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+		},
+		{
+			name: "From empty",
+			a:    "",
+			b:    "one\ntwo",
+			want: "@@ -0,0 +1,2 @@\n+one\n+two\n",
+		},
+		{
+			name: "Windows line endings",
+			a:    "one\r\ntwo\r\n",
+			b:    "one\ntwo\nthree\n",
+			want: "@@ -1,2 +1,3 @@\n one\n two\n+three\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, unified(Hunks(tt.a, tt.b, 3)), tt.want)
+		})
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		a         string
+		b         string
+		wantEqual int
+	}{
+		{
+			name:      "Moved block",
+			a:         "a b c d e f g",
+			b:         "e f g a b c d",
+			wantEqual: 4,
+		},
+		{
+			name:      "Interleaved",
+			a:         "a b c a b b a",
+			b:         "c b a b a c",
+			wantEqual: 4,
+		},
+		{
+			name:      "Nothing in common",
+			a:         "a b c",
+			b:         "d e",
+			wantEqual: 0,
+		},
+		{
+			name:      "Repeated lines",
+			a:         "x x y x x y x",
+			b:         "y x x x y x y y",
+			wantEqual: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+
+			// Keeping the equal and deleted lines must give back a, and
+			// keeping the equal and inserted ones must give b.
+			var gotA, gotB []string
+			equal := 0
+			for _, line := range Lines(a, b) {
+				if line.Kind != Insert {
+					gotA = append(gotA, line.Text)
+				}
+				if line.Kind != Delete {
+					gotB = append(gotB, line.Text)
+				}
+				if line.Kind == Equal {
+					equal++
+				}
+			}
+
+			assert.Equal(t, strings.Join(gotA, " "), tt.a)
+			assert.Equal(t, strings.Join(gotB, " "), tt.b)
+			assert.Equal(t, equal, tt.wantEqual)
+		})
+	}
+}
+
+func TestHunks(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, fmt.Sprint(i))
+	}
+	a := strings.Join(lines, "\n")
+
+	// Changes more than six lines apart are put in separate hunks, each with
+	// three lines of context.
+	lines[1] = "two"
+	lines[17] = "eighteen"
+	b := strings.Join(lines, "\n")
+
+	hunks := Hunks(a, b, 3)
+	assert.Equal(t, len(hunks), 2)
+	assert.Equal(t, hunks[0].Header(), "@@ -1,5 +1,5 @@")
+	assert.Equal(t, hunks[1].Header(), "@@ -15,6 +15,6 @@")
+
+	// Changes closer together than that share a hunk.
+	lines[17] = "18"
+	lines[7] = "eight"
+	b = strings.Join(lines, "\n")
+
+	hunks = Hunks(a, b, 3)
+	assert.Equal(t, len(hunks), 1)
+	assert.Equal(t, hunks[0].Header(), "@@ -1,11 +1,11 @@")
+}
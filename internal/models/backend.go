@@ -0,0 +1,142 @@
+package models
+
+import (
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Backend 标识保存数据的数据库。MySQL 是默认的后端；SQLite 使用纯 Go 驱动，
+// 数据保存在单个文件中，不需要数据库服务器，适合小团队和 CI
+type Backend string
+
+const (
+	MySQL  Backend = "mysql"
+	SQLite Backend = "sqlite"
+)
+
+// sqliteSchema creates the tables for the SQLite backend if they don't exist.
+//
+//go:embed schema_sqlite.sql
+var sqliteSchema string
+
+// sqliteParams configure every connection to an SQLite database:
+//
+//   - foreign_keys is off by default in SQLite, and the ON DELETE clauses
+//     depend on it.
+//   - busy_timeout makes a connection wait for the write lock held by
+//     another one, instead of failing straight away, and WAL mode lets
+//     readers carry on while a write is in progress.
+//   - _time_format writes times in a format which sorts in time order, so
+//     that they can be compared in queries.
+//   - _txlock=immediate takes the write lock when a transaction begins. It
+//     stands in for the row locks which the transactions rely on in MySQL,
+//     like the SELECT ... FOR UPDATE in Burn().
+const sqliteParams = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)" +
+	"&_time_format=sqlite&_txlock=immediate"
+
+// Open 打开 backend 数据库的连接池并确认可以连接。MySQL 的 dsn 是驱动的数据源名称；
+// SQLite 的 dsn 是数据库文件的路径，文件和表不存在时会被创建
+func Open(backend Backend, dsn string) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+
+	switch backend {
+	case MySQL:
+		db, err = sql.Open("mysql", dsn)
+	case SQLite:
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		db, err = sql.Open("sqlite", dsn+sep+sqliteParams)
+	default:
+		return nil, fmt.Errorf("models: unknown backend %q", backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// The MySQL schema is managed with the migrations, but an SQLite database
+	// is usually a new file, so the schema is created here.
+	if backend == SQLite {
+		_, err = db.Exec(sqliteSchema)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
+
+// backendOf returns the backend of a connection pool, so that the models can
+// be created with just the pool, as they were before there was a choice.
+func backendOf(db *sql.DB) Backend {
+	if _, ok := db.Driver().(*sqlite.Driver); ok {
+		return SQLite
+	}
+	return MySQL
+}
+
+// now returns an SQL expression for the current UTC time, to the second. In
+// SQLite it's formatted in the same way as the times written by the driver.
+func (b Backend) now() string {
+	if b == SQLite {
+		return `strftime('%Y-%m-%d %H:%M:%S+00:00', 'now')`
+	}
+	return `UTC_TIMESTAMP()`
+}
+
+// notExpired 是筛选未过期代码片段的查询条件，expires 为 NULL 的片段永不过期
+func (b Backend) notExpired() string {
+	return `(s.expires IS NULL OR s.expires > ` + b.now() + `)`
+}
+
+// insertIgnore returns the start of an INSERT statement which skips rows that
+// would violate a unique constraint.
+func (b Backend) insertIgnore() string {
+	if b == SQLite {
+		return "INSERT OR IGNORE"
+	}
+	return "INSERT IGNORE"
+}
+
+// forUpdate returns the locking clause for a SELECT whose rows are about to be
+// changed in the same transaction. SQLite has no row locks, and doesn't need
+// them since its transactions take the write lock when they begin.
+func (b Backend) forUpdate() string {
+	if b == SQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// isDuplicate reports whether err is a violation of a unique key. The key
+// isn't checked, because not every MySQL server names it in the message, so
+// callers must only use this for tables with one unique key.
+func isDuplicate(err error) bool {
+	var mySQLError *mysql.MySQLError
+	if errors.As(err, &mySQLError) {
+		return mySQLError.Number == 1062
+	}
+
+	var sqliteError *sqlite.Error
+	if errors.As(err, &sqliteError) {
+		return sqliteError.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+
+	return false
+}
@@ -12,4 +12,12 @@ var (
 	// Add a new ErrDuplicateEmail error. We'll use this later if a user
 	// tries to signup with an email address that's already in use.
 	ErrDuplicateEmail = errors.New("models: duplicate email")
+
+	// ErrBurned is returned when a burn after reading snippet has already
+	// been viewed and destroyed.
+	ErrBurned = errors.New("models: snippet has been burned")
+
+	// ErrAccountDisabled is returned when the correct credentials are given
+	// for a user whose account has been disabled.
+	ErrAccountDisabled = errors.New("models: account disabled")
 )
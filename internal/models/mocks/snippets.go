@@ -1,34 +1,348 @@
 package mocks
 
 import (
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"snippetbox.xmxxmx.us/internal/models"
 )
 
 var mockSnippet = models.Snippet{
-	ID:      1,
-	Title:   "An old silent pond",
-	Content: "An old silent pond...",
-	Created: time.Now(),
-	Expires: time.Now(),
+	ID:         1,
+	Slug:       "q7Yx2LpK0aZ",
+	Title:      "An old silent pond",
+	Content:    "An old silent pond...",
+	Created:    time.Now(),
+	Updated:    time.Date(2025, 7, 5, 18, 48, 0, 0, time.UTC),
+	Expires:    time.Now().Add(time.Hour),
+	UserID:     1,
+	UserName:   "Alice Jones",
+	Tags:       []string{"haiku", "poetry"},
+	Visibility: models.VisibilityPublic,
+	Forks:      1,
 }
 
-type SnippetModel struct{}
+var mockPrivateSnippet = models.Snippet{
+	ID:         3,
+	Slug:       "Vn3_cR8sWd-",
+	Title:      "A frog jumps in",
+	Content:    "A frog jumps in...",
+	Created:    time.Now(),
+	Expires:    time.Now().Add(time.Hour),
+	UserID:     1,
+	UserName:   "Alice Jones",
+	Visibility: models.VisibilityPrivate,
+	ForkedFrom: 1,
+}
+
+var mockBurnSnippet = models.Snippet{
+	ID:               4,
+	Slug:             "Bz0kT5rYq2M",
+	Title:            "Staging password",
+	Content:          "correct horse battery staple",
+	Created:          time.Now(),
+	Expires:          time.Now().Add(time.Hour),
+	UserID:           1,
+	UserName:         "Alice Jones",
+	Visibility:       models.VisibilityUnlisted,
+	BurnAfterReading: true,
+}
+
+var mockBurnedSnippet = models.Snippet{
+	ID:               5,
+	Slug:             "Xd7pL1mNv8C",
+	Title:            "Production password",
+	Created:          time.Now(),
+	Expires:          time.Now().Add(time.Hour),
+	UserID:           1,
+	UserName:         "Alice Jones",
+	Visibility:       models.VisibilityUnlisted,
+	BurnAfterReading: true,
+	Burned:           true,
+}
+
+var mockProtectedSnippet = models.Snippet{
+	ID:         6,
+	Slug:       "Pw9rT2kLm4Q",
+	Title:      "Meeting point",
+	Content:    "Meet at the old pond at dawn",
+	Created:    time.Now(),
+	Expires:    time.Now().Add(time.Hour),
+	UserID:     1,
+	UserName:   "Alice Jones",
+	Visibility: models.VisibilityUnlisted,
+	Protected:  true,
+}
+
+var mockPublicProtectedSnippet = models.Snippet{
+	ID:         8,
+	Slug:       "Wf8kN3pXs6J",
+	Title:      "Guest Wi-Fi",
+	Content:    "The guest network password is hunter2",
+	Created:    time.Now(),
+	Expires:    time.Now().Add(time.Hour),
+	UserID:     1,
+	UserName:   "Alice Jones",
+	Visibility: models.VisibilityPublic,
+	Protected:  true,
+}
+
+var mockExpiredSnippet = models.Snippet{
+	ID:         7,
+	Slug:       "Ex4pQ7wNc2R",
+	Title:      "Last week's standup notes",
+	Content:    "Nothing to report",
+	Created:    time.Now().Add(-14 * 24 * time.Hour),
+	Expires:    time.Now().Add(-7 * 24 * time.Hour),
+	UserID:     1,
+	UserName:   "Alice Jones",
+	Visibility: models.VisibilityPublic,
+}
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-	return 2, nil
+var mockRevisions = []models.Revision{
+	{
+		SnippetID: 1,
+		Number:    2,
+		Title:     "An old silent pond",
+		Content:   "An old silent pond...",
+		UserID:    1,
+		UserName:  "Alice Jones",
+		Created:   time.Now(),
+	},
+	{
+		SnippetID: 1,
+		Number:    1,
+		Title:     "An old pond",
+		Content:   "An old pond...",
+		UserID:    1,
+		UserName:  "Alice Jones",
+		Created:   time.Now(),
+	},
+}
+
+// SnippetModel remembers the last snippet passed to Insert(), so that it can be
+// fetched again with Get() or GetBySlug() like a real inserted snippet. It also
+// remembers whether the burn after reading snippet has been burned, so that
+// burning it a second time fails like it would in the database.
+type SnippetModel struct {
+	mu       sync.Mutex
+	inserted *models.Snippet
+	burned   bool
+}
+
+func (m *SnippetModel) Insert(snippet models.NewSnippet) (int, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inserted = &models.Snippet{
+		ID:         2,
+		Slug:       "Hm4tE9wQz1B",
+		Title:      snippet.Title,
+		Content:    snippet.Content,
+		Created:    time.Now(),
+		Updated:    time.Now(),
+		Expires:    snippet.Expires,
+		UserID:     snippet.UserID,
+		Tags:       snippet.Tags,
+		Language:   snippet.Language,
+		Visibility: snippet.Visibility,
+
+		BurnAfterReading: snippet.BurnAfterReading,
+		Protected:        snippet.Password != "",
+		ForkedFrom:       snippet.ForkedFrom,
+	}
+
+	return 2, "Hm4tE9wQz1B", nil
+}
+
+// lastInserted returns the snippet added by Insert(), if there is one.
+func (m *SnippetModel) lastInserted() (models.Snippet, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.inserted == nil {
+		return models.Snippet{}, false
+	}
+	return *m.inserted, true
 }
 
 func (m *SnippetModel) Get(id int) (models.Snippet, error) {
 	switch id {
 	case 1:
 		return mockSnippet, nil
+	case 3:
+		return mockPrivateSnippet, nil
+	case 4:
+		return mockBurnSnippet, nil
+	case 5:
+		return mockBurnedSnippet, nil
+	case 6:
+		return mockProtectedSnippet, nil
+	case 8:
+		return mockPublicProtectedSnippet, nil
+	case 2:
+		if s, ok := m.lastInserted(); ok {
+			return s, nil
+		}
+		return models.Snippet{}, models.ErrNoRecord
 	default:
 		return models.Snippet{}, models.ErrNoRecord
 	}
 }
 
-func (m *SnippetModel) Latest() ([]models.Snippet, error) {
-	return []models.Snippet{mockSnippet}, nil
+// GetForOwner returns the same snippets as Get(), and the expired one too.
+func (m *SnippetModel) GetForOwner(id int) (models.Snippet, error) {
+	if id == mockExpiredSnippet.ID {
+		return mockExpiredSnippet, nil
+	}
+	return m.Get(id)
+}
+
+// GetBySlugForOwner returns the same snippets as GetBySlug(), and the expired
+// one too.
+func (m *SnippetModel) GetBySlugForOwner(slug string) (models.Snippet, error) {
+	if slug == mockExpiredSnippet.Slug {
+		return mockExpiredSnippet, nil
+	}
+	return m.GetBySlug(slug)
+}
+
+func (m *SnippetModel) GetBySlug(slug string) (models.Snippet, error) {
+	switch slug {
+	case mockSnippet.Slug:
+		return mockSnippet, nil
+	case mockPrivateSnippet.Slug:
+		return mockPrivateSnippet, nil
+	case mockBurnSnippet.Slug:
+		return mockBurnSnippet, nil
+	case mockBurnedSnippet.Slug:
+		return mockBurnedSnippet, nil
+	case mockProtectedSnippet.Slug:
+		return mockProtectedSnippet, nil
+	case mockPublicProtectedSnippet.Slug:
+		return mockPublicProtectedSnippet, nil
+	case "Hm4tE9wQz1B":
+		if s, ok := m.lastInserted(); ok {
+			return s, nil
+		}
+		return models.Snippet{}, models.ErrNoRecord
+	default:
+		return models.Snippet{}, models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) Burn(id int) (models.Snippet, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch id {
+	case 4:
+		if m.burned {
+			return models.Snippet{}, models.ErrBurned
+		}
+		m.burned = true
+		return mockBurnSnippet, nil
+	case 5:
+		return models.Snippet{}, models.ErrBurned
+	default:
+		return models.Snippet{}, models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) CheckPassword(id int, password string) error {
+	if id != mockProtectedSnippet.ID && id != mockPublicProtectedSnippet.ID {
+		return models.ErrNoRecord
+	}
+	if password != "pa55word" {
+		return models.ErrInvalidCredentials
+	}
+	return nil
+}
+
+func (m *SnippetModel) Update(id int, userID int, title string, content string) error {
+	switch id {
+	case 1, 7:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) UpdateExpiry(id int, expires time.Time) error {
+	switch id {
+	case 1, 7:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) DeleteExpired(before time.Time, limit int) (int, error) {
+	return 0, nil
+}
+
+func (m *SnippetModel) Revisions(snippetID int) ([]models.Revision, error) {
+	switch snippetID {
+	case 1:
+		return mockRevisions, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *SnippetModel) Revision(snippetID int, number int) (models.Revision, error) {
+	for _, r := range mockRevisions {
+		if r.SnippetID == snippetID && r.Number == number {
+			return r, nil
+		}
+	}
+	return models.Revision{}, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Delete(id int) error {
+	switch id {
+	case 1, 7:
+		return nil
+	default:
+		return models.ErrNoRecord
+	}
+}
+
+func (m *SnippetModel) ForUser(userID int, sort string) ([]models.Snippet, error) {
+	switch userID {
+	case 1:
+		return []models.Snippet{mockSnippet, mockPrivateSnippet, mockExpiredSnippet}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (m *SnippetModel) List(filter models.SnippetFilter) ([]models.Snippet, models.Page, error) {
+	var snippets []models.Snippet
+	for _, s := range []models.Snippet{mockSnippet, mockPublicProtectedSnippet} {
+		if filter.Tag == "" || slices.Contains(s.Tags, filter.Tag) {
+			snippets = append(snippets, s)
+		}
+	}
+
+	return snippets, models.Page{}, nil
+}
+
+func (m *SnippetModel) Search(query string, page int, pageSize int) ([]models.Snippet, models.Page, error) {
+	query = strings.ToLower(query)
+
+	var snippets []models.Snippet
+	for _, s := range []models.Snippet{mockSnippet, mockPublicProtectedSnippet} {
+		// Like the real model, never search password-protected snippets.
+		if s.Protected {
+			continue
+		}
+		if strings.Contains(strings.ToLower(s.Title), query) || strings.Contains(strings.ToLower(s.Content), query) {
+			snippets = append(snippets, s)
+		}
+	}
+
+	return snippets, models.Page{}, nil
 }
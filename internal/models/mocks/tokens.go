@@ -0,0 +1,60 @@
+package mocks
+
+import (
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/models"
+)
+
+// The API tokens known to the mock: one for user 1 with both scopes, and a
+// read only one for user 2.
+var mockTokens = map[string]models.APIToken{
+	"sbx_aliceReadWrite": {
+		ID:      1,
+		UserID:  1,
+		Name:    "Laptop",
+		Scopes:  []string{models.ScopeRead, models.ScopeWrite},
+		Created: time.Now(),
+	},
+	"sbx_bobReadOnly": {
+		ID:      2,
+		UserID:  2,
+		Name:    "Backups",
+		Scopes:  []string{models.ScopeRead},
+		Created: time.Now(),
+		Expires: time.Now().Add(24 * time.Hour),
+	},
+}
+
+type TokenModel struct{}
+
+func (m *TokenModel) Insert(userID int, name string, scopes []string, expires time.Time) (string, error) {
+	return "sbx_newTokenShownOnce", nil
+}
+
+func (m *TokenModel) Authenticate(token string) (models.APIToken, error) {
+	t, ok := mockTokens[token]
+	if !ok {
+		return models.APIToken{}, models.ErrInvalidCredentials
+	}
+	return t, nil
+}
+
+func (m *TokenModel) ForUser(userID int) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	for _, t := range mockTokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *TokenModel) Delete(id int, userID int) error {
+	for _, t := range mockTokens {
+		if t.ID == id && t.UserID == userID {
+			return nil
+		}
+	}
+	return models.ErrNoRecord
+}
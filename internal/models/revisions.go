@@ -0,0 +1,85 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Revision 是代码片段的一个历史版本。Number 从 1 开始，在同一个片段内递增，
+// UserID 和 UserName 是创建该版本的用户
+type Revision struct {
+	SnippetID int
+	Number    int
+	Title     string
+	Content   string
+	UserID    int
+	UserName  string
+	Created   time.Time
+}
+
+// addRevision 在事务中把代码片段的标题和内容记录为它的下一个修订
+func addRevision(tx *sql.Tx, b Backend, snippetID, userID int, title, content string) error {
+	stmt := `INSERT INTO snippet_revisions (snippet_id, revision, title, content, user_id, created)
+    SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ?, ?, ` + b.now() + `
+    FROM snippet_revisions WHERE snippet_id = ?`
+
+	_, err := tx.Exec(stmt, snippetID, title, content, userID, snippetID)
+	return err
+}
+
+// revisionColumns 是返回修订的查询共用的字段列表，顺序与 scanRevision() 一致
+const revisionColumns = `r.snippet_id, r.revision, r.title, r.content, r.user_id, u.name, r.created`
+
+// scanRevision 把一行 revisionColumns 查询结果复制到新的 Revision 结构体中
+func scanRevision(row scanner) (Revision, error) {
+	var r Revision
+	err := row.Scan(&r.SnippetID, &r.Number, &r.Title, &r.Content, &r.UserID, &r.UserName, &r.Created)
+	return r, err
+}
+
+// Revisions 获取指定代码片段的全部修订，最新的修订在前
+func (m *SnippetModel) Revisions(snippetID int) ([]Revision, error) {
+	stmt := `SELECT ` + revisionColumns + `
+    FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+    WHERE r.snippet_id = ? ORDER BY r.revision DESC`
+
+	rows, err := m.DB.Query(stmt, snippetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Revision 获取指定代码片段的某一个修订
+func (m *SnippetModel) Revision(snippetID int, number int) (Revision, error) {
+	stmt := `SELECT ` + revisionColumns + `
+    FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+    WHERE r.snippet_id = ? AND r.revision = ?`
+
+	r, err := scanRevision(m.DB.QueryRow(stmt, snippetID, number))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Revision{}, ErrNoRecord
+		}
+		return Revision{}, err
+	}
+
+	return r, nil
+}
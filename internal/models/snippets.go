@@ -1,25 +1,130 @@
 package models
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 type SnippetModelInterface interface {
-	Insert(title string, content string, expires int) (int, error)
+	Insert(snippet NewSnippet) (int, string, error)
 	Get(id int) (Snippet, error)
-	Latest() ([]Snippet, error)
+	GetBySlug(slug string) (Snippet, error)
+	GetForOwner(id int) (Snippet, error)
+	GetBySlugForOwner(slug string) (Snippet, error)
+	Burn(id int) (Snippet, error)
+	CheckPassword(id int, password string) error
+	UpdateExpiry(id int, expires time.Time) error
+	Revisions(snippetID int) ([]Revision, error)
+	Revision(snippetID int, number int) (Revision, error)
+	DeleteExpired(before time.Time, limit int) (int, error)
+	Update(id int, userID int, title string, content string) error
+	Delete(id int) error
+	ForUser(userID int, sort string) ([]Snippet, error)
+	List(filter SnippetFilter) ([]Snippet, Page, error)
+	Search(query string, page int, pageSize int) ([]Snippet, Page, error)
 }
 
+// 代码片段的可见性：public 会出现在首页等公开列表中，unlisted 只能通过链接
+// 访问，private 只有创建者本人可以查看
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// UserSnippetSorts 列出 ForUser() 支持的排序方式，前缀 "-" 表示降序
+var UserSnippetSorts = []string{"-created", "created", "-expires", "expires"}
+
 // Snippet 定义代码片段结构体，用于存储单个代码片段的数据
 // 结构体字段与 MySQL 数据库中 snippets 表的字段一一对应
+// UserID 记录创建该片段的用户，UserName 通过关联 users 表查询得到
+// Expires 为零值表示片段永不过期
+// Slug 是随机生成、不可猜测的标识符，用于对外分享的链接
+// BurnAfterReading 的片段在第一次被他人查看后销毁，Burned 表示它已经被销毁
+// Protected 表示查看片段需要输入访问密码，密码的哈希值不会被加载到结构体中
+// ForkedFrom 是复刻来源片段的 ID，0 表示不是复刻的；Forks 是该片段被复刻的次数，
+// Updated 是最后一次修订的时间，这两个字段只有 Get() 和 GetBySlug() 会加载
 type Snippet struct {
-	ID      int
-	Title   string
-	Content string
-	Created time.Time
-	Expires time.Time
+	ID         int
+	Slug       string
+	Title      string
+	Content    string
+	Created    time.Time
+	Updated    time.Time
+	Expires    time.Time
+	UserID     int
+	UserName   string
+	Tags       []string
+	Language   string
+	Visibility string
+
+	BurnAfterReading bool
+	Burned           bool
+	Protected        bool
+
+	ForkedFrom int
+	Forks      int
+}
+
+// NewSnippet 保存创建代码片段所需的数据，Expires 是过期时间，零值表示永不过期，
+// Language 是代码高亮使用的语言名称，空字符串表示纯文本，
+// Password 是可选的明文访问密码，为空表示不需要密码，
+// ForkedFrom 是复刻来源片段的 ID，0 表示新建的片段
+type NewSnippet struct {
+	Title      string
+	Content    string
+	Expires    time.Time
+	UserID     int
+	Tags       []string
+	Language   string
+	Visibility string
+
+	BurnAfterReading bool
+	Password         string
+	ForkedFrom       int
+}
+
+// 分页时每页默认和最多返回的代码片段数量
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// SnippetFilter 定义 List() 的分页游标、每页数量和按创建时间、标签过滤的条件。
+// Before 和 After 是基于 id 的游标：Before 返回 id 更小（更旧）的片段，
+// After 返回 id 更大（更新）的片段，两者都为 0 时返回最新的一页。
+type SnippetFilter struct {
+	Before      int
+	After       int
+	PageSize    int
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Tag         string
+}
+
+// Page 保存翻页所需的游标，值为 0 表示该方向上没有更多数据。
+// List() 返回的游标是片段 id，Search() 返回的游标是页码。
+type Page struct {
+	NextCursor int
+	PrevCursor int
+}
+
+// Expired 判断代码片段是否已经过期，永不过期的片段总是返回 false
+func (s Snippet) Expired() bool {
+	return !s.Expires.IsZero() && !s.Expires.After(time.Now())
+}
+
+// VisibleTo 判断指定用户能否查看该代码片段，userID 为 0 表示匿名用户
+func (s Snippet) VisibleTo(userID int) bool {
+	return s.Visibility != VisibilityPrivate || s.UserID == userID
 }
 
 // SnippetModel 定义代码片段模型结构体，封装数据库连接池
@@ -27,58 +132,260 @@ type SnippetModel struct {
 	DB *sql.DB
 }
 
-// Insert 向数据库插入新的代码片段
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
+// snippetColumns 是所有返回完整代码片段的查询共用的字段列表，顺序与
+// scanSnippet() 一致。查询中 snippets 表的别名必须是 s，users 表的别名必须是 u。
+const snippetColumns = `s.id, s.slug, s.title, s.content, s.created, s.expires, s.user_id, u.name,
+    s.language, s.visibility, s.burn_after_reading, s.burned, s.hashed_password IS NOT NULL,
+    COALESCE(s.forked_from, 0)`
+
+// nullTime 把零值时间转换为 NULL，用于写入可以为空的 expires 字段
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// scanner 是 *sql.Row 和 *sql.Rows 都实现了的接口
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSnippet 把一行 snippetColumns 查询结果复制到新的 Snippet 结构体中
+func scanSnippet(row scanner) (Snippet, error) {
+	// Initialize a new zeroed Snippet struct.
+	var s Snippet
+
+	// The expires column is NULL for snippets which never expire, which can't
+	// be scanned straight into a time.Time. We scan it into a sql.NullTime
+	// instead and leave s.Expires as the zero time if it's NULL.
+	var expires sql.NullTime
+
+	// Use Scan() to copy the values from each field in the row to the
+	// corresponding field in the Snippet struct. Notice that the arguments
+	// to Scan() are *pointers* to the place you want to copy the data into,
+	// and the number of arguments must be exactly the same as the number of
+	// columns in snippetColumns.
+	err := row.Scan(&s.ID, &s.Slug, &s.Title, &s.Content, &s.Created, &expires, &s.UserID, &s.UserName,
+		&s.Language, &s.Visibility, &s.BurnAfterReading, &s.Burned,
+		&s.Protected, &s.ForkedFrom)
+	s.Expires = expires.Time
+	return s, err
+}
+
+// querySnippets 执行查询语句并返回结果中的全部代码片段
+func (m *SnippetModel) querySnippets(stmt string, args ...any) ([]Snippet, error) {
+	// Use the Query() method on the connection pool to execute our
+	// SQL statement. This returns a sql.Rows resultset containing the result of
+	// our query.
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// We defer rows.Close() to ensure the sql.Rows resultset is
+	// always properly closed before the method returns. This defer
+	// statement should come *after* you check for an error from the Query()
+	// method. Otherwise, if Query() returns an error, you'll get a panic
+	// trying to close a nil resultset.
+	defer rows.Close()
+
+	// Initialize an empty slice to hold the Snippet structs.
+	var snippets []Snippet
+
+	// Use rows.Next to iterate through the rows in the resultset. This
+	// prepares the first (and then each subsequent) row to be acted on by the
+	// rows.Scan() method. If iteration over all the rows completes then the
+	// resultset automatically closes itself and frees up the underlying
+	// database connection.
+	for rows.Next() {
+		s, err := scanSnippet(rows)
+		if err != nil {
+			return nil, err
+		}
+		snippets = append(snippets, s)
+	}
+
+	// When the rows.Next() loop has finished we call rows.Err() to retrieve any
+	// error that was encountered during the iteration. It's important to
+	// call this - don't assume the iteration completed successfully over the
+	// entire result set.
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return snippets, nil
+}
+
+// slugBytes 是生成 slug 时使用的随机字节数，编码后得到 11 个字符
+const slugBytes = 8
+
+// newSlug 生成一个随机的、URL 安全的 slug，并确认它还没有被其他代码片段使用
+func newSlug(tx *sql.Tx) (string, error) {
+	// A collision between 64-bit random values is extremely unlikely, but we
+	// check anyway and try again a few times before giving up. The UNIQUE
+	// constraint on the slug column is the final safeguard.
+	for range 5 {
+		b := make([]byte, slugBytes)
+		_, err := rand.Read(b)
+		if err != nil {
+			return "", err
+		}
+		slug := base64.RawURLEncoding.EncodeToString(b)
+
+		// Old links use the integer ID in the same position in the URL as the
+		// slug, so a slug must never look like an integer.
+		if _, err := strconv.Atoi(slug); err == nil {
+			continue
+		}
+
+		var exists bool
+		err = tx.QueryRow("SELECT EXISTS(SELECT true FROM snippets WHERE slug = ?)", slug).Scan(&exists)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return slug, nil
+		}
+	}
+
+	return "", errors.New("models: unable to generate a unique slug")
+}
+
+// Insert 向数据库插入新的代码片段及其标签，返回新片段的 ID 和 slug
+func (m *SnippetModel) Insert(snippet NewSnippet) (int, string, error) {
+	b := backendOf(m.DB)
+
+	// The snippet and its tags are written in a single transaction, so that we
+	// never end up with a snippet which is missing some of its tags.
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Defer a call to tx.Rollback() to ensure it is always called before the
+	// function returns. If the transaction has already been committed this is
+	// a no-op.
+	defer tx.Rollback()
+
+	slug, err := newSlug(tx)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Protected snippets store a bcrypt hash of their access password, in the
+	// same way as user passwords. Unprotected snippets store NULL.
+	var hashedPassword []byte
+	if snippet.Password != "" {
+		hashedPassword, err = bcrypt.GenerateFromPassword([]byte(snippet.Password), 12)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
 	// Write the SQL statement we want to execute. I've split it over two lines
 	// for readability (which is why it's surrounded with backquotes instead
 	// of normal double quotes).
-	stmt := `INSERT INTO snippets (title, content, created, expires) 
-    VALUES (?,?,UTC_TIMESTAMP(),DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+	stmt := `INSERT INTO snippets (slug, title, content, created, expires, user_id, language, visibility,
+    burn_after_reading, hashed_password, forked_from)
+    VALUES (?,?,?,` + b.now() + `,?,?,?,?,?,?,?)`
 
-	// Use the Exec() method on the embedded connection pool to execute the
-	// statement. The first parameter is the SQL statement, followed by the
-	// values for the placeholder parameters: title, content and expiry in
-	// that order. This method returns a sql.Result type, which contains some
-	// basic information about what happened when the statement was executed.
-	result, err := m.DB.Exec(stmt, title, content, expires)
+	// Use the Exec() method on the transaction to execute the statement. The
+	// first parameter is the SQL statement, followed by the values for the
+	// placeholder parameters: slug, title, content, expiry, the owner's user
+	// ID, the language, the visibility, the burn after reading flag, the
+	// hashed password and the snippet it was forked from in that order. This
+	// method returns a sql.Result type, which contains some basic information
+	// about what happened when the statement was executed.
+	forkedFrom := sql.NullInt64{Int64: int64(snippet.ForkedFrom), Valid: snippet.ForkedFrom != 0}
+	result, err := tx.Exec(stmt, slug, snippet.Title, snippet.Content, nullTime(snippet.Expires), snippet.UserID,
+		snippet.Language, snippet.Visibility, snippet.BurnAfterReading, hashedPassword, forkedFrom)
 	if err != nil {
-		return 0, err
+		return 0, "", err
 	}
 
 	// Use the LastInsertId() method on the result to get the ID of our
 	// newly inserted record in the snippets table.
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, err
+		return 0, "", err
+	}
+
+	// Record the snippet as it was created as its first revision.
+	err = addRevision(tx, b, int(id), snippet.UserID, snippet.Title, snippet.Content)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Create any tags which don't exist yet, and then link each of them to
+	// the new snippet. A repeated tag is skipped, because linking it twice
+	// would violate the snippet_tags primary key.
+	for i, tag := range snippet.Tags {
+		if slices.Contains(snippet.Tags[:i], tag) {
+			continue
+		}
+
+		_, err = tx.Exec(b.insertIgnore()+" INTO tags (name) VALUES (?)", tag)
+		if err != nil {
+			return 0, "", err
+		}
+
+		stmt = `INSERT INTO snippet_tags (snippet_id, tag_id)
+        SELECT ?, id FROM tags WHERE name = ?`
+
+		_, err = tx.Exec(stmt, id, tag)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, "", err
 	}
 
 	// The ID returned has the type int64, so we convert it to an int type
 	// before returning.
-	return int(id), nil
+	return int(id), slug, nil
 }
 
-// Get 根据 ID 获取指定的代码片段
+// Get 根据 ID 获取指定的代码片段，不论它的可见性如何，调用方需要自行检查访问权限
 func (m *SnippetModel) Get(id int) (Snippet, error) {
+	return m.get(backendOf(m.DB).notExpired()+" AND s.id = ?", id)
+}
+
+// GetBySlug 根据 slug 获取指定的代码片段，与 Get 一样不检查可见性
+func (m *SnippetModel) GetBySlug(slug string) (Snippet, error) {
+	return m.get(backendOf(m.DB).notExpired()+" AND s.slug = ?", slug)
+}
+
+// GetForOwner 根据 ID 获取指定的代码片段，与 Get 不同的是它也返回已经过期的片段，
+// 用于所有者编辑、删除片段或延长它的过期时间，调用方需要自行检查所有者
+func (m *SnippetModel) GetForOwner(id int) (Snippet, error) {
+	return m.get("s.id = ?", id)
+}
+
+// GetBySlugForOwner 根据 slug 获取指定的代码片段，与 GetForOwner 一样也返回已经
+// 过期的片段
+func (m *SnippetModel) GetBySlugForOwner(slug string) (Snippet, error) {
+	return m.get("s.slug = ?", slug)
+}
+
+// get 返回满足 where 条件的代码片段及其标签，where 中只能有一个占位符
+func (m *SnippetModel) get(where string, arg any) (Snippet, error) {
 	// Write the SQL statement we want to execute. Again, I've split it over two
-	// lines for readability.
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() AND id = ?`
+	// lines for readability. We join against the users table so that the
+	// author's name is returned alongside the snippet.
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE ` + where
 
 	// Use the QueryRow() method on the connection pool to execute our
-	// SQL statement, passing in the untrusted id variable as the value for the
+	// SQL statement, passing in the untrusted arg variable as the value for the
 	// placeholder parameter. This returns a pointer to a sql.Row value which
 	// holds the result from the database.
-	row := m.DB.QueryRow(stmt, id)
+	row := m.DB.QueryRow(stmt, arg)
 
-	// Initialize a new zeroed Snippet struct.
-	var s Snippet
-
-	// Use row.Scan() to copy the values from each field in sql.Row to the
-	// corresponding field in the Snippet struct. Notice that the arguments
-	// to row.Scan are *pointers* to the place you want to copy the data into,
-	// and the number of arguments must be exactly the same as the number of
-	// columns returned by your statement.
-	err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+	// Use the scanSnippet() helper to copy the values from the sql.Row into
+	// a new Snippet struct.
+	s, err := scanSnippet(row)
 	if err != nil {
 		// If the query returns no rows, then row.Scan() will return a
 		// sql.ErrNoRows error. We use the errors.Is() function check for that
@@ -91,61 +398,431 @@ func (m *SnippetModel) Get(id int) (Snippet, error) {
 		}
 	}
 
+	// Fetch the names of the tags attached to the snippet.
+	s.Tags, err = m.tags(s.ID)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	// Count the number of times the snippet has been forked.
+	err = m.DB.QueryRow("SELECT COUNT(*) FROM snippets WHERE forked_from = ?", s.ID).Scan(&s.Forks)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	// The snippet was last modified when its latest revision was saved.
+	// Burned snippets have no revisions left, so for them we fall back to the
+	// time it was created. (We don't use MAX(created) here, because SQLite
+	// returns the result of MAX() as text rather than as a time.)
+	stmt = `SELECT created FROM snippet_revisions WHERE snippet_id = ?
+    ORDER BY revision DESC LIMIT 1`
+
+	err = m.DB.QueryRow(stmt, s.ID).Scan(&s.Updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.Updated = s.Created
+	} else if err != nil {
+		return Snippet{}, err
+	}
+
 	// If everything went OK, then return the filled Snippet struct
 	return s, nil
 }
 
-// Latest 获取最新创建的 10 个代码片段
-func (m *SnippetModel) Latest() ([]Snippet, error) {
-	// Write the SQL statement we want to execute.
-	stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+// tags 获取指定代码片段的全部标签，按名称排序
+func (m *SnippetModel) tags(snippetID int) ([]string, error) {
+	stmt := `SELECT t.name FROM tags t
+    INNER JOIN snippet_tags st ON st.tag_id = t.id
+    WHERE st.snippet_id = ? ORDER BY t.name`
 
-	// Use the Query() method on the connection pool to execute our
-	// SQL statement. This returns a sql.Rows resultset containing the result of
-	// our query.
-	rows, err := m.DB.Query(stmt)
+	rows, err := m.DB.Query(stmt, snippetID)
 	if err != nil {
 		return nil, err
 	}
-
-	// We defer rows.Close() to ensure the sql.Rows resultset is
-	// always properly closed before the Latest() method returns. This defer
-	// statement should come *after* you check for an error from the Query()
-	// method. Otherwise, if Query() returns an error, you'll get a panic
-	// trying to close a nil resultset.
 	defer rows.Close()
 
-	// Initialize an empty slice to hold the Snippet structs.
-	var snippets []Snippet
+	var tags []string
 
-	// Use rows.Next to iterate through the rows in the resultset. This
-	// prepares the first (and then each subsequent) row to be acted on by the
-	// rows.Scan() method. If iteration over all the rows completes then the
-	// resultset automatically closes itself and frees up the underlying
-	// database connection.
 	for rows.Next() {
-		// Create a new zero value Snippet struct.
-		var s Snippet
-		// Use rows.Scan() to copy the values from each field in the row to the
-		// new Snippet struct that we created. Again, the arguments to row.Scan()
-		// must be pointers to the place you want to copy the data into, and the
-		// number of arguments must be exactly the same as the number of
-		// columns returned by your statement.
-		err = rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+		var tag string
+		err = rows.Scan(&tag)
 		if err != nil {
 			return nil, err
 		}
-		snippets = append(snippets, s)
+		tags = append(tags, tag)
 	}
 
-	// When the rows.Next() loop has finished we call rows.Err() to retrieve any
-	// error that was encountered during the iteration. It's important to
-	// call this - don't assume the iteration completed successfully over the
-	// entire result set.
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return snippets, nil
+	return tags, nil
+}
+
+// Burn 返回指定代码片段的内容，并在同一个事务中将其销毁：内容被清空，
+// 只留下一条标记为已销毁的记录。如果片段已经被销毁，返回 ErrBurned
+func (m *SnippetModel) Burn(id int) (Snippet, error) {
+	b := backendOf(m.DB)
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return Snippet{}, err
+	}
+	defer tx.Rollback()
+
+	// Lock the row with FOR UPDATE, so that if two people open the snippet at
+	// the same moment only one of them gets to see its content. In SQLite the
+	// transaction holds the write lock for the whole database instead.
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE ` + b.notExpired() + ` AND s.id = ?` + b.forUpdate()
+
+	s, err := scanSnippet(tx.QueryRow(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Snippet{}, ErrNoRecord
+		} else {
+			return Snippet{}, err
+		}
+	}
+	if s.Burned {
+		return Snippet{}, ErrBurned
+	}
+
+	s.Tags, err = m.tags(s.ID)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	// The title is kept so that the owner can still tell which of their
+	// snippets has been read, but the content, tags and revisions are gone
+	// for good.
+	_, err = tx.Exec("UPDATE snippets SET content = '', burned = TRUE WHERE id = ?", id)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	_, err = tx.Exec("DELETE FROM snippet_tags WHERE snippet_id = ?", id)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	// The old revisions hold copies of the content, so they have to go too.
+	_, err = tx.Exec("DELETE FROM snippet_revisions WHERE snippet_id = ?", id)
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Snippet{}, err
+	}
+
+	return s, nil
+}
+
+// CheckPassword 检查访问密码是否与受保护的代码片段匹配，不匹配时返回
+// ErrInvalidCredentials，片段不存在、已过期或没有设置密码时返回 ErrNoRecord
+func (m *SnippetModel) CheckPassword(id int, password string) error {
+	var hashedPassword []byte
+
+	stmt := `SELECT s.hashed_password FROM snippets s
+    WHERE ` + backendOf(m.DB).notExpired() + ` AND s.hashed_password IS NOT NULL AND s.id = ?`
+
+	err := m.DB.QueryRow(stmt, id).Scan(&hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoRecord
+		}
+		return err
+	}
+
+	// Check whether the hashed password and plain-text password provided
+	// match, exactly as UserModel.Authenticate() does.
+	err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Update 修改指定代码片段的标题和内容，并把修改后的版本记录为新的修订，
+// userID 是做出修改的用户
+func (m *SnippetModel) Update(id int, userID int, title string, content string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE snippets SET title = ?, content = ? WHERE id = ?`
+
+	// Note that we don't check the number of rows affected here: MySQL
+	// reports zero affected rows when the new values are identical to the old
+	// ones, so callers should confirm the snippet exists with Get() first.
+	// The UPDATE also locks the snippet's row until the transaction ends, so
+	// concurrent edits can't be given the same revision number.
+	_, err = tx.Exec(stmt, title, content, id)
+	if err != nil {
+		return err
+	}
+
+	err = addRevision(tx, backendOf(m.DB), id, userID, title, content)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateExpiry 修改指定代码片段的过期时间，零值表示永不过期
+func (m *SnippetModel) UpdateExpiry(id int, expires time.Time) error {
+	stmt := `UPDATE snippets SET expires = ? WHERE id = ?`
+
+	_, err := m.DB.Exec(stmt, nullTime(expires), id)
+	return err
+}
+
+// Delete 删除指定的代码片段
+func (m *SnippetModel) Delete(id int) error {
+	stmt := `DELETE FROM snippets WHERE id = ?`
+
+	result, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	// If no rows were deleted then there was no snippet with that ID, so
+	// return our ErrNoRecord error.
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// DeleteExpired 删除在 before 之前过期的代码片段，每次最多删除 limit 个，
+// 返回实际删除的数量。永不过期的片段不会被删除
+func (m *SnippetModel) DeleteExpired(before time.Time, limit int) (int, error) {
+	// Deleting in bounded batches keeps each statement (and the locks it
+	// holds) short, even when there's a large backlog of expired snippets.
+	// Their tags are removed by the ON DELETE CASCADE on snippet_tags.
+	stmt := `DELETE FROM snippets WHERE expires IS NOT NULL AND expires < ?
+    ORDER BY expires LIMIT ?`
+
+	// SQLite doesn't support ORDER BY and LIMIT in a DELETE (unless it's
+	// compiled with an option that the pure-Go driver doesn't use), but
+	// unlike MySQL it does support them in an IN subquery.
+	if backendOf(m.DB) == SQLite {
+		stmt = `DELETE FROM snippets WHERE id IN (
+        SELECT id FROM snippets WHERE expires IS NOT NULL AND expires < ?
+        ORDER BY expires LIMIT ?)`
+	}
+
+	result, err := m.DB.Exec(stmt, before.UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(rows), nil
+}
+
+// ForUser 获取指定用户创建的全部代码片段，包括已经过期的片段
+func (m *SnippetModel) ForUser(userID int, sort string) ([]Snippet, error) {
+	// The ORDER BY clause can't use a placeholder parameter, so we map the
+	// sort value onto a fixed set of clauses instead of interpolating it.
+	// Each clause also sorts by id so that the order is deterministic.
+	// Snippets which never expire (a NULL expires) are treated as expiring
+	// after all the others.
+	var orderBy string
+	switch sort {
+	case "created":
+		orderBy = "s.created ASC, s.id ASC"
+	case "-expires":
+		orderBy = "s.expires IS NULL DESC, s.expires DESC, s.id DESC"
+	case "expires":
+		orderBy = "s.expires IS NULL ASC, s.expires ASC, s.id ASC"
+	default:
+		orderBy = "s.created DESC, s.id DESC"
+	}
+
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE s.user_id = ? ORDER BY ` + orderBy
+
+	return m.querySnippets(stmt, userID)
+}
+
+// List 按 id 倒序分页获取未过期的公开代码片段，使用游标（keyset）分页
+func (m *SnippetModel) List(filter SnippetFilter) ([]Snippet, Page, error) {
+	pageSize := filter.PageSize
+	if pageSize < 1 || pageSize > MaxPageSize {
+		pageSize = DefaultPageSize
+	}
+
+	// Build up the WHERE clause and its placeholder arguments depending on
+	// which filters have been set. Only public snippets are ever listed, and
+	// never burn after reading ones.
+	conditions := []string{backendOf(m.DB).notExpired(), "s.visibility = ?", "NOT s.burn_after_reading"}
+	args := []any{VisibilityPublic}
+
+	if !filter.CreatedFrom.IsZero() {
+		conditions = append(conditions, "s.created >= ?")
+		args = append(args, filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		conditions = append(conditions, "s.created < ?")
+		args = append(args, filter.CreatedTo)
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM snippet_tags st
+        INNER JOIN tags t ON t.id = st.tag_id
+        WHERE st.snippet_id = s.id AND t.name = ?)`)
+		args = append(args, filter.Tag)
+	}
+
+	// When paging backwards we need the snippets immediately after the cursor,
+	// so we read them in ascending order and reverse them afterwards.
+	order := "DESC"
+	switch {
+	case filter.Before > 0:
+		conditions = append(conditions, "s.id < ?")
+		args = append(args, filter.Before)
+	case filter.After > 0:
+		conditions = append(conditions, "s.id > ?")
+		args = append(args, filter.After)
+		order = "ASC"
+	}
+
+	// We fetch one more row than we need, so that we know whether there is
+	// another page in the direction that we're paging.
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    WHERE ` + strings.Join(conditions, " AND ") + `
+    ORDER BY s.id ` + order + ` LIMIT ?`
+	args = append(args, pageSize+1)
+
+	snippets, err := m.querySnippets(stmt, args...)
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	hasMore := len(snippets) > pageSize
+	if hasMore {
+		snippets = snippets[:pageSize]
+	}
+
+	if filter.After > 0 {
+		slices.Reverse(snippets)
+	}
+
+	if len(snippets) == 0 {
+		return snippets, Page{}, nil
+	}
+
+	// A cursor in the request means we arrived from a neighbouring page, so
+	// there's always a page to go back to in that direction.
+	var page Page
+	newest, oldest := snippets[0].ID, snippets[len(snippets)-1].ID
+
+	if filter.After > 0 {
+		if hasMore {
+			page.PrevCursor = newest
+		}
+		page.NextCursor = oldest
+	} else {
+		if hasMore {
+			page.NextCursor = oldest
+		}
+		if filter.Before > 0 {
+			page.PrevCursor = newest
+		}
+	}
+
+	return snippets, page, nil
+}
+
+// Search 使用全文索引在标题和内容中搜索未过期、没有设置密码的公开代码片段，按相关度排序
+func (m *SnippetModel) Search(query string, page int, pageSize int) ([]Snippet, Page, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > MaxPageSize {
+		pageSize = DefaultPageSize
+	}
+
+	b := backendOf(m.DB)
+
+	// Search results are ordered by relevance rather than by id, so we can't
+	// use a keyset cursor here and page through the results with an OFFSET
+	// instead. The relevance scores are calculated in a derived table
+	// because the MATCH() columns must exactly match those in the
+	// idx_snippets_fulltext index.
+	matches := `SELECT id, MATCH(title, content) AGAINST (? IN NATURAL LANGUAGE MODE) AS score
+        FROM snippets WHERE MATCH(title, content) AGAINST (? IN NATURAL LANGUAGE MODE)`
+	args := []any{query, query}
+
+	// In SQLite the scores come from the snippets_fts table instead. bm25()
+	// gives better matches lower scores, so it's negated to sort in the same
+	// order as MySQL.
+	if b == SQLite {
+		query = ftsQuery(query)
+		if query == "" {
+			return nil, Page{}, nil
+		}
+		matches = `SELECT rowid AS id, -bm25(snippets_fts) AS score
+        FROM snippets_fts WHERE snippets_fts MATCH ?`
+		args = []any{query}
+	}
+
+	// As in List(), we fetch one extra row to find out whether there is a
+	// next page. Password-protected snippets are left out altogether, because
+	// the fact that one matches would reveal which words its content holds.
+	stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s INNER JOIN users u ON u.id = s.user_id
+    INNER JOIN (` + matches + `) matches ON matches.id = s.id
+    WHERE ` + b.notExpired() + ` AND s.visibility = ? AND NOT s.burn_after_reading
+    AND s.hashed_password IS NULL
+    ORDER BY matches.score DESC, s.id DESC LIMIT ? OFFSET ?`
+	args = append(args, VisibilityPublic, pageSize+1, (page-1)*pageSize)
+
+	snippets, err := m.querySnippets(stmt, args...)
+	if err != nil {
+		return nil, Page{}, err
+	}
+
+	var p Page
+	if len(snippets) > pageSize {
+		snippets = snippets[:pageSize]
+		p.NextCursor = page + 1
+	}
+	if page > 1 {
+		p.PrevCursor = page - 1
+	}
+
+	return snippets, p, nil
+}
+
+// ftsQuery turns a search query into an FTS5 query which, like MySQL's natural
+// language mode, matches snippets containing any of its words. Each word is
+// quoted, so that characters such as - and * are searched for rather than
+// treated as FTS5 syntax.
+func ftsQuery(query string) string {
+	words := strings.Fields(query)
+	for i, w := range words {
+		words[i] = `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " OR ")
 }
@@ -0,0 +1,534 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+func TestSnippetModelGet(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		tests := []struct {
+			name      string
+			snippetID int
+			wantTitle string
+			wantUser  string
+			wantErr   error
+		}{
+			{
+				name:      "Valid ID",
+				snippetID: 1,
+				wantTitle: "An old silent pond",
+				wantUser:  "Alice Jones",
+			},
+			{
+				name:      "Non-existent ID",
+				snippetID: 2,
+				wantErr:   ErrNoRecord,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				db := newTestDB(t, backend)
+
+				m := SnippetModel{db}
+
+				s, err := m.Get(tt.snippetID)
+
+				assert.Equal(t, err, tt.wantErr)
+				assert.Equal(t, s.Title, tt.wantTitle)
+				assert.Equal(t, s.UserName, tt.wantUser)
+			})
+		}
+	})
+}
+
+func TestSnippetModelGetForOwner(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		// An expired snippet is hidden from Get(), but its owner must still
+		// be able to fetch it to edit, delete or renew it.
+		id, slug, err := m.Insert(NewSnippet{Title: "Expired", Content: "Expired content", Expires: inDays(-1), UserID: 1, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+
+		_, err = m.Get(id)
+		assert.Equal(t, err, ErrNoRecord)
+
+		s, err := m.GetForOwner(id)
+		assert.NilError(t, err)
+		assert.Equal(t, s.Title, "Expired")
+		assert.Equal(t, s.UserName, "Alice Jones")
+		assert.Equal(t, s.Expired(), true)
+
+		_, err = m.GetForOwner(id + 1)
+		assert.Equal(t, err, ErrNoRecord)
+
+		_, err = m.GetBySlug(slug)
+		assert.Equal(t, err, ErrNoRecord)
+
+		s, err = m.GetBySlugForOwner(slug)
+		assert.NilError(t, err)
+		assert.Equal(t, s.ID, id)
+	})
+}
+
+func TestSnippetModelInsert(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		id, slug, err := m.Insert(NewSnippet{
+			Title:      "Title",
+			Content:    "Content",
+			Expires:    inDays(7),
+			UserID:     1,
+			Tags:       []string{"sql", "poetry"},
+			Language:   "sql",
+			Visibility: VisibilityPublic,
+		})
+		assert.NilError(t, err)
+
+		s, err := m.Get(id)
+		assert.NilError(t, err)
+		assert.Equal(t, s.UserID, 1)
+		assert.Equal(t, s.Content, "Content")
+		assert.Equal(t, fmt.Sprint(s.Tags), "[poetry sql]")
+		assert.Equal(t, s.Language, "sql")
+		assert.Equal(t, s.Slug, slug)
+		assert.Equal(t, len(slug), 11)
+
+		s, err = m.GetBySlug(slug)
+		assert.NilError(t, err)
+		assert.Equal(t, s.ID, id)
+
+		_, err = m.GetBySlug("q7Yx2LpK0aZ")
+		assert.NilError(t, err)
+
+		_, err = m.GetBySlug("xxxxxxxxxxx")
+		assert.Equal(t, err, ErrNoRecord)
+
+		// Both snippets are tagged "poetry", but only the new one is tagged "sql".
+		snippets, _, err := m.List(SnippetFilter{Tag: "poetry"})
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 2)
+
+		snippets, _, err = m.List(SnippetFilter{Tag: "sql"})
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 1)
+		assert.Equal(t, snippets[0].ID, id)
+
+		// A repeated tag is only linked to the snippet once.
+		id, _, err = m.Insert(NewSnippet{Title: "Title", Content: "Content", Expires: inDays(7), UserID: 1, Tags: []string{"go", "go"}, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+
+		s, err = m.Get(id)
+		assert.NilError(t, err)
+		assert.Equal(t, fmt.Sprint(s.Tags), "[go]")
+	})
+}
+
+func TestSnippetModelUpdateDelete(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		err := m.Update(1, 1, "New title", "New content")
+		assert.NilError(t, err)
+
+		s, err := m.Get(1)
+		assert.NilError(t, err)
+		assert.Equal(t, s.Title, "New title")
+		assert.Equal(t, s.Content, "New content")
+
+		err = m.Delete(1)
+		assert.NilError(t, err)
+
+		_, err = m.Get(1)
+		assert.Equal(t, err, ErrNoRecord)
+
+		err = m.Delete(1)
+		assert.Equal(t, err, ErrNoRecord)
+	})
+}
+
+func TestSnippetModelForUser(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		// Insert a snippet which has already expired. It should still be
+		// returned by ForUser(), even though Get() and List() hide it.
+		id, _, err := m.Insert(NewSnippet{Title: "Expired", Content: "Expired content", Expires: inDays(-1), UserID: 1, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+
+		snippets, err := m.ForUser(1, "-expires")
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 2)
+		assert.Equal(t, snippets[0].ID, 1)
+		assert.Equal(t, snippets[1].ID, id)
+		assert.Equal(t, snippets[1].Expired(), true)
+
+		snippets, err = m.ForUser(2, "-created")
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 0)
+	})
+}
+
+func TestSnippetModelList(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		// Add four more snippets, so that there are five in total with the IDs
+		// 1 to 5.
+		for range 4 {
+			_, _, err := m.Insert(NewSnippet{Title: "Title", Content: "Content", Expires: inDays(7), UserID: 1, Visibility: VisibilityPublic})
+			assert.NilError(t, err)
+		}
+
+		ids := func(snippets []Snippet) []int {
+			var ids []int
+			for _, s := range snippets {
+				ids = append(ids, s.ID)
+			}
+			return ids
+		}
+
+		snippets, page, err := m.List(SnippetFilter{PageSize: 2})
+		assert.NilError(t, err)
+		assert.Equal(t, fmt.Sprint(ids(snippets)), "[5 4]")
+		assert.Equal(t, page, Page{NextCursor: 4})
+
+		snippets, page, err = m.List(SnippetFilter{PageSize: 2, Before: 4})
+		assert.NilError(t, err)
+		assert.Equal(t, fmt.Sprint(ids(snippets)), "[3 2]")
+		assert.Equal(t, page, Page{NextCursor: 2, PrevCursor: 3})
+
+		snippets, page, err = m.List(SnippetFilter{PageSize: 2, Before: 2})
+		assert.NilError(t, err)
+		assert.Equal(t, fmt.Sprint(ids(snippets)), "[1]")
+		assert.Equal(t, page, Page{PrevCursor: 1})
+
+		snippets, page, err = m.List(SnippetFilter{PageSize: 2, After: 1})
+		assert.NilError(t, err)
+		assert.Equal(t, fmt.Sprint(ids(snippets)), "[3 2]")
+		assert.Equal(t, page, Page{NextCursor: 2, PrevCursor: 3})
+
+		// The seeded snippet was created in 2022, before the others.
+		snippets, _, err = m.List(SnippetFilter{
+			CreatedFrom: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			CreatedTo:   time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC),
+		})
+		assert.NilError(t, err)
+		assert.Equal(t, fmt.Sprint(ids(snippets)), "[1]")
+	})
+}
+
+func TestSnippetModelSearch(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		id, _, err := m.Insert(NewSnippet{Title: "nginx config", Content: "server { listen 80; }", Expires: inDays(7), UserID: 1, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+
+		snippets, page, err := m.Search("nginx", 1, 10)
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 1)
+		assert.Equal(t, snippets[0].ID, id)
+		assert.Equal(t, page, Page{})
+
+		// Punctuation in the query is searched for, not treated as syntax.
+		snippets, _, err = m.Search(`nginx:"`, 1, 10)
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 1)
+
+		snippets, _, err = m.Search("kubernetes", 1, 10)
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 0)
+
+		// Password-protected snippets are never found, not even by their
+		// title, since a match would give away words from their content.
+		_, _, err = m.Insert(NewSnippet{Title: "nginx secrets", Content: "certificate passphrase", Expires: inDays(7), UserID: 1, Visibility: VisibilityPublic, Password: "pa55word"})
+		assert.NilError(t, err)
+
+		snippets, _, err = m.Search("nginx", 1, 10)
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 1)
+		assert.Equal(t, snippets[0].ID, id)
+
+		snippets, _, err = m.Search("certificate", 1, 10)
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 0)
+	})
+}
+
+func TestSnippetModelVisibility(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		for _, visibility := range []string{VisibilityUnlisted, VisibilityPrivate} {
+			id, _, err := m.Insert(NewSnippet{
+				Title:      "An old silent pond",
+				Content:    "Hidden content",
+				Expires:    inDays(7),
+				UserID:     1,
+				Tags:       []string{"poetry"},
+				Visibility: visibility,
+			})
+			assert.NilError(t, err)
+
+			// The snippet can be fetched directly...
+			s, err := m.Get(id)
+			assert.NilError(t, err)
+			assert.Equal(t, s.Visibility, visibility)
+		}
+
+		// ...but only the seeded public snippet is listed or found by a search.
+		snippets, _, err := m.List(SnippetFilter{})
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 1)
+
+		snippets, _, err = m.List(SnippetFilter{Tag: "poetry"})
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 1)
+
+		snippets, _, err = m.Search("hidden", 1, 10)
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 0)
+
+		snippets, err = m.ForUser(1, "-created")
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 3)
+	})
+}
+
+func TestSnippetModelBurn(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		id, slug, err := m.Insert(NewSnippet{
+			Title:            "Staging password",
+			Content:          "correct horse battery staple",
+			Expires:          inDays(7),
+			UserID:           1,
+			Tags:             []string{"secret"},
+			Visibility:       VisibilityPublic,
+			BurnAfterReading: true,
+		})
+		assert.NilError(t, err)
+
+		// Burn after reading snippets are never listed, even when public.
+		snippets, _, err := m.List(SnippetFilter{})
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 1)
+
+		s, err := m.Burn(id)
+		assert.NilError(t, err)
+		assert.Equal(t, s.Content, "correct horse battery staple")
+		assert.Equal(t, fmt.Sprint(s.Tags), "[secret]")
+
+		// Only a tombstone is left behind.
+		s, err = m.GetBySlug(slug)
+		assert.NilError(t, err)
+		assert.Equal(t, s.Burned, true)
+		assert.Equal(t, s.Content, "")
+		assert.Equal(t, len(s.Tags), 0)
+
+		revisions, err := m.Revisions(id)
+		assert.NilError(t, err)
+		assert.Equal(t, len(revisions), 0)
+
+		_, err = m.Burn(id)
+		assert.Equal(t, err, ErrBurned)
+
+		_, err = m.Burn(99)
+		assert.Equal(t, err, ErrNoRecord)
+	})
+}
+
+func TestSnippetModelCheckPassword(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		id, slug, err := m.Insert(NewSnippet{
+			Title:      "Meeting point",
+			Content:    "Meet at the old pond at dawn",
+			Expires:    inDays(7),
+			UserID:     1,
+			Visibility: VisibilityUnlisted,
+			Password:   "pa55word",
+		})
+		assert.NilError(t, err)
+
+		s, err := m.GetBySlug(slug)
+		assert.NilError(t, err)
+		assert.Equal(t, s.Protected, true)
+
+		assert.NilError(t, m.CheckPassword(id, "pa55word"))
+		assert.Equal(t, m.CheckPassword(id, "wrong password"), ErrInvalidCredentials)
+
+		// The seeded snippet has no password, so there's nothing to check.
+		assert.Equal(t, m.CheckPassword(1, "pa55word"), ErrNoRecord)
+	})
+}
+
+func TestSnippetModelExpiry(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		id, _, err := m.Insert(NewSnippet{Title: "Forever", Content: "Forever content", UserID: 1, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+
+		// A snippet without an expiry never expires.
+		s, err := m.Get(id)
+		assert.NilError(t, err)
+		assert.Equal(t, s.Expires.IsZero(), true)
+		assert.Equal(t, s.Expired(), false)
+
+		snippets, err := m.ForUser(1, "-expires")
+		assert.NilError(t, err)
+		assert.Equal(t, snippets[0].ID, id)
+
+		expires := time.Date(2100, 1, 1, 12, 30, 0, 0, time.UTC)
+		err = m.UpdateExpiry(id, expires)
+		assert.NilError(t, err)
+
+		s, err = m.Get(id)
+		assert.NilError(t, err)
+		assert.Equal(t, s.Expires.Equal(expires), true)
+
+		err = m.UpdateExpiry(id, inDays(-1))
+		assert.NilError(t, err)
+
+		_, err = m.Get(id)
+		assert.Equal(t, err, ErrNoRecord)
+	})
+}
+
+func TestSnippetModelDeleteExpired(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		for range 3 {
+			_, _, err := m.Insert(NewSnippet{Title: "Expired", Content: "Expired content", Expires: inDays(-2), UserID: 1, Tags: []string{"old"}, Visibility: VisibilityPublic})
+			assert.NilError(t, err)
+		}
+
+		_, _, err := m.Insert(NewSnippet{Title: "Recent", Content: "Recently expired", Expires: inDays(0).Add(-time.Hour), UserID: 1, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+
+		_, _, err = m.Insert(NewSnippet{Title: "Forever", Content: "Forever content", UserID: 1, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+
+		// Only the snippets which expired before the cut off are deleted, at most
+		// limit at a time.
+		before := inDays(-1)
+
+		n, err := m.DeleteExpired(before, 2)
+		assert.NilError(t, err)
+		assert.Equal(t, n, 2)
+
+		n, err = m.DeleteExpired(before, 2)
+		assert.NilError(t, err)
+		assert.Equal(t, n, 1)
+
+		n, err = m.DeleteExpired(before, 2)
+		assert.NilError(t, err)
+		assert.Equal(t, n, 0)
+
+		snippets, err := m.ForUser(1, "-created")
+		assert.NilError(t, err)
+		assert.Equal(t, len(snippets), 3)
+	})
+}
+
+func TestSnippetModelRevisions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		id, _, err := m.Insert(NewSnippet{Title: "Draft", Content: "First draft", Expires: inDays(7), UserID: 1, Visibility: VisibilityPublic})
+		assert.NilError(t, err)
+
+		err = m.Update(id, 1, "Final", "Final version")
+		assert.NilError(t, err)
+
+		revisions, err := m.Revisions(id)
+		assert.NilError(t, err)
+		assert.Equal(t, len(revisions), 2)
+		assert.Equal(t, revisions[0].Number, 2)
+		assert.Equal(t, revisions[0].Content, "Final version")
+		assert.Equal(t, revisions[0].UserName, "Alice Jones")
+		assert.Equal(t, revisions[1].Number, 1)
+		assert.Equal(t, revisions[1].Title, "Draft")
+
+		r, err := m.Revision(id, 1)
+		assert.NilError(t, err)
+		assert.Equal(t, r.Content, "First draft")
+
+		_, err = m.Revision(id, 3)
+		assert.Equal(t, err, ErrNoRecord)
+
+		// The snippet was last updated when its latest revision was saved.
+		s, err := m.Get(id)
+		assert.NilError(t, err)
+		assert.Equal(t, s.Updated, revisions[0].Created)
+
+		// The revisions of the seeded snippet are separate.
+		revisions, err = m.Revisions(1)
+		assert.NilError(t, err)
+		assert.Equal(t, len(revisions), 1)
+	})
+}
+
+func TestSnippetModelFork(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := SnippetModel{db}
+
+		id, _, err := m.Insert(NewSnippet{Title: "My pond", Content: "An old silent pond...", Expires: inDays(7), UserID: 1, Visibility: VisibilityPublic, ForkedFrom: 1})
+		assert.NilError(t, err)
+
+		s, err := m.Get(id)
+		assert.NilError(t, err)
+		assert.Equal(t, s.ForkedFrom, 1)
+		assert.Equal(t, s.Forks, 0)
+
+		s, err = m.Get(1)
+		assert.NilError(t, err)
+		assert.Equal(t, s.ForkedFrom, 0)
+		assert.Equal(t, s.Forks, 1)
+
+		// Deleting the original keeps the fork, but forgets where it came from.
+		err = m.Delete(1)
+		assert.NilError(t, err)
+
+		s, err = m.Get(id)
+		assert.NilError(t, err)
+		assert.Equal(t, s.ForkedFrom, 0)
+	})
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// Stats 保存数据库中各类记录的数量，由管理工具输出
+type Stats struct {
+	Users           int
+	DisabledUsers   int
+	Snippets        int
+	ActiveSnippets  int
+	ExpiredSnippets int
+	BurnedSnippets  int
+	Revisions       int
+	Tags            int
+	APITokens       int
+}
+
+// StatsModel 定义统计模型结构体，封装数据库连接池
+type StatsModel struct {
+	DB *sql.DB
+}
+
+// Get 统计数据库中各类记录的数量
+func (m *StatsModel) Get() (Stats, error) {
+	var s Stats
+
+	notExpired := backendOf(m.DB).notExpired()
+
+	// Each count is a separate subquery, so that the whole lot can be
+	// fetched in a single round trip.
+	stmt := `SELECT
+        (SELECT COUNT(*) FROM users),
+        (SELECT COUNT(*) FROM users WHERE disabled),
+        (SELECT COUNT(*) FROM snippets),
+        (SELECT COUNT(*) FROM snippets s WHERE ` + notExpired + ` AND NOT s.burned),
+        (SELECT COUNT(*) FROM snippets s WHERE NOT ` + notExpired + `),
+        (SELECT COUNT(*) FROM snippets WHERE burned),
+        (SELECT COUNT(*) FROM snippet_revisions),
+        (SELECT COUNT(*) FROM tags),
+        (SELECT COUNT(*) FROM api_tokens)`
+
+	err := m.DB.QueryRow(stmt).Scan(&s.Users, &s.DisabledUsers, &s.Snippets, &s.ActiveSnippets,
+		&s.ExpiredSnippets, &s.BurnedSnippets, &s.Revisions, &s.Tags, &s.APITokens)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+func TestStatsModelGet(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		// Add an expired snippet alongside the one in the test data.
+		_, _, err := (&SnippetModel{db}).Insert(NewSnippet{
+			Title:   "Expired",
+			Content: "Gone",
+			Expires: time.Now().Add(-time.Hour),
+			UserID:  1,
+		})
+		assert.NilError(t, err)
+
+		m := StatsModel{db}
+
+		stats, err := m.Get()
+		assert.NilError(t, err)
+		assert.Equal(t, stats, Stats{
+			Users:           1,
+			Snippets:        2,
+			ActiveSnippets:  1,
+			ExpiredSnippets: 1,
+			Revisions:       2,
+			Tags:            1,
+		})
+	})
+}
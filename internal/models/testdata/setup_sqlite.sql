@@ -0,0 +1,30 @@
+-- The same rows as setup.sql, for the SQLite backend. The tables are created
+-- by Open(), so only the data is inserted here.
+INSERT INTO users (name, email, hashed_password, created) VALUES (
+    'Alice Jones',
+    'alice@example.com',
+    '$2a$12$NuTjWXm3KKntReFwyBVHyuf/to.HEwTy.eS206TNfkGfr6HzGJSWG',
+    '2022-01-01 09:18:24+00:00'
+);
+
+INSERT INTO snippets (slug, title, content, created, expires, user_id) VALUES (
+    'q7Yx2LpK0aZ',
+    'An old silent pond',
+    'An old silent pond...',
+    '2022-01-01 10:00:00+00:00',
+    '2099-01-01 10:00:00+00:00',
+    1
+);
+
+INSERT INTO tags (name) VALUES ('poetry');
+
+INSERT INTO snippet_tags (snippet_id, tag_id) VALUES (1, 1);
+
+INSERT INTO snippet_revisions (snippet_id, revision, title, content, user_id, created) VALUES (
+    1,
+    1,
+    'An old silent pond',
+    'An old silent pond...',
+    1,
+    '2022-01-01 10:00:00+00:00'
+);
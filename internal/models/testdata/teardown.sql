@@ -1,3 +1,11 @@
-DROP TABLE users;
+DROP TABLE api_tokens;
 
-DROP TABLE snippets;
\ No newline at end of file
+DROP TABLE snippet_revisions;
+
+DROP TABLE snippet_tags;
+
+DROP TABLE tags;
+
+DROP TABLE snippets;
+
+DROP TABLE users;
\ No newline at end of file
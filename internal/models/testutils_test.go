@@ -3,10 +3,33 @@ package models
 import (
 	"database/sql"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
-func newTestDB(t *testing.T) *sql.DB {
+// forEachBackend runs fn as a sub-test for each database backend, so that
+// every model test checks that both backends behave the same. The MySQL
+// sub-tests need a database server, and are skipped with the "-short" flag.
+func forEachBackend(t *testing.T, fn func(t *testing.T, backend Backend)) {
+	t.Run("MySQL", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("models: skipping integration test")
+		}
+		fn(t, MySQL)
+	})
+	t.Run("SQLite", func(t *testing.T) {
+		fn(t, SQLite)
+	})
+}
+
+// newTestDB returns a connection pool to a test database for backend,
+// containing the data in the setup script.
+func newTestDB(t *testing.T, backend Backend) *sql.DB {
+	if backend == SQLite {
+		return newSQLiteTestDB(t)
+	}
+
 	// Establish a sql.DB connection pool for our test database. Because our
 	// setup and teardown scripts contain multiple SQL statements, we need
 	// to use the "multiStatements=true" parameter in our DSN. This instructs
@@ -52,3 +75,31 @@ func newTestDB(t *testing.T) *sql.DB {
 	// Return the database connection pool.
 	return db
 }
+
+// newSQLiteTestDB returns a connection pool to a new SQLite database in a
+// temporary directory. Open() creates the tables, so the setup script only
+// inserts the data, and there's no need to tear anything down: the directory
+// is removed when the test has finished.
+func newSQLiteTestDB(t *testing.T) *sql.DB {
+	db, err := Open(SQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	script, err := os.ReadFile("./testdata/setup_sqlite.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(string(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// inDays returns the time n days from now, for use as a snippet expiry.
+func inDays(n int) time.Time {
+	return time.Now().Add(time.Duration(n) * 24 * time.Hour)
+}
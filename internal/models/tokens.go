@@ -0,0 +1,189 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+type TokenModelInterface interface {
+	Insert(userID int, name string, scopes []string, expires time.Time) (string, error)
+	Authenticate(token string) (APIToken, error)
+	ForUser(userID int) ([]APIToken, error)
+	Delete(id int, userID int) error
+}
+
+// API 令牌的权限范围：read 可以读取代码片段，write 可以创建、修改和删除代码片段
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// TokenPrefix is the start of every API token, which makes the tokens easy to
+// recognise, for example by secret scanners.
+const TokenPrefix = "sbx_"
+
+// APIToken 定义个人 API 令牌结构体，令牌本身只在创建时返回一次，数据库中只保存
+// 它的哈希值。LastUsed 为零值表示从未使用过，Expires 为零值表示永不过期。
+type APIToken struct {
+	ID       int
+	UserID   int
+	Name     string
+	Scopes   []string
+	Created  time.Time
+	LastUsed time.Time
+	Expires  time.Time
+}
+
+// HasScope 判断令牌是否拥有指定的权限范围
+func (t APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Expired 判断令牌是否已经过期，永不过期的令牌总是返回 false
+func (t APIToken) Expired() bool {
+	return !t.Expires.IsZero() && !t.Expires.After(time.Now())
+}
+
+// TokenModel 定义 API 令牌模型结构体，封装数据库连接池
+type TokenModel struct {
+	DB *sql.DB
+}
+
+// hashToken returns the hex encoded SHA-256 hash of a token. Unlike passwords
+// the tokens are long random strings, so a fast hash is enough to keep them
+// safe, and it lets a token be looked up by its hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Insert 为用户创建一个新的 API 令牌并返回令牌本身，expires 为零值表示永不过期
+func (m *TokenModel) Insert(userID int, name string, scopes []string, expires time.Time) (string, error) {
+	// The token is 32 random bytes, which is far too many to guess.
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	token := TokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	stmt := `INSERT INTO api_tokens (user_id, name, token_hash, scopes, created, expires)
+    VALUES (?, ?, ?, ?, ` + backendOf(m.DB).now() + `, ?)`
+
+	_, err = m.DB.Exec(stmt, userID, name, hashToken(token), strings.Join(scopes, ","), nullTime(expires))
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Authenticate 查找与 token 对应的未过期令牌，并记录它的使用时间。如果令牌不存在、
+// 已经过期或者它的用户已被禁用，返回 ErrInvalidCredentials。
+func (m *TokenModel) Authenticate(token string) (APIToken, error) {
+	b := backendOf(m.DB)
+
+	stmt := `SELECT ` + tokenColumns + ` FROM api_tokens
+    WHERE token_hash = ? AND (expires IS NULL OR expires > ` + b.now() + `)
+    AND user_id IN (SELECT id FROM users WHERE NOT disabled)`
+
+	t, err := scanToken(m.DB.QueryRow(stmt, hashToken(token)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return APIToken{}, ErrInvalidCredentials
+		}
+		return APIToken{}, err
+	}
+
+	// Recording the last used time on every request would mean a write for
+	// each API call, so it's only updated once a minute.
+	minuteAgo := `UTC_TIMESTAMP() - INTERVAL 1 MINUTE`
+	if b == SQLite {
+		minuteAgo = `strftime('%Y-%m-%d %H:%M:%S+00:00', 'now', '-1 minute')`
+	}
+	stmt = `UPDATE api_tokens SET last_used = ` + b.now() + `
+    WHERE id = ? AND (last_used IS NULL OR last_used < ` + minuteAgo + `)`
+
+	_, err = m.DB.Exec(stmt, t.ID)
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	return t, nil
+}
+
+// ForUser 返回用户的全部 API 令牌，包括已经过期的，最新创建的排在最前面
+func (m *TokenModel) ForUser(userID int) ([]APIToken, error) {
+	stmt := `SELECT ` + tokenColumns + ` FROM api_tokens
+    WHERE user_id = ? ORDER BY id DESC`
+
+	rows, err := m.DB.Query(stmt, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Delete 撤销用户的一个 API 令牌，如果令牌不存在或者属于其他用户，返回 ErrNoRecord
+func (m *TokenModel) Delete(id int, userID int) error {
+	result, err := m.DB.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
+}
+
+// tokenColumns 是返回 API 令牌的查询共用的字段列表，顺序与 scanToken() 一致
+const tokenColumns = `id, user_id, name, scopes, created, last_used, expires`
+
+// scanToken 把一行 tokenColumns 查询结果复制到新的 APIToken 结构体中
+func scanToken(row scanner) (APIToken, error) {
+	var t APIToken
+	var scopes string
+	var lastUsed, expires sql.NullTime
+
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &t.Created, &lastUsed, &expires)
+	if err != nil {
+		return APIToken{}, err
+	}
+
+	if scopes != "" {
+		t.Scopes = strings.Split(scopes, ",")
+	}
+	t.LastUsed = lastUsed.Time
+	t.Expires = expires.Time
+
+	return t, nil
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+func TestTokenModel(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+
+		m := TokenModel{db}
+
+		token, err := m.Insert(1, "Laptop", []string{ScopeRead, ScopeWrite}, time.Time{})
+		assert.NilError(t, err)
+		assert.Equal(t, strings.HasPrefix(token, TokenPrefix), true)
+
+		// Only the hash of the token is stored.
+		var hashes int
+		err = db.QueryRow("SELECT COUNT(*) FROM api_tokens WHERE token_hash = ?", token).Scan(&hashes)
+		assert.NilError(t, err)
+		assert.Equal(t, hashes, 0)
+
+		tok, err := m.Authenticate(token)
+		assert.NilError(t, err)
+		assert.Equal(t, tok.UserID, 1)
+		assert.Equal(t, tok.Name, "Laptop")
+		assert.Equal(t, tok.HasScope(ScopeWrite), true)
+		assert.Equal(t, tok.Expires.IsZero(), true)
+
+		_, err = m.Authenticate(token + "x")
+		assert.Equal(t, err, ErrInvalidCredentials)
+
+		// Expired tokens can't be used, but are still listed.
+		expired, err := m.Insert(1, "Old", []string{ScopeRead}, inDays(-1))
+		assert.NilError(t, err)
+
+		_, err = m.Authenticate(expired)
+		assert.Equal(t, err, ErrInvalidCredentials)
+
+		tokens, err := m.ForUser(1)
+		assert.NilError(t, err)
+		assert.Equal(t, len(tokens), 2)
+		assert.Equal(t, tokens[0].Name, "Old")
+		assert.Equal(t, tokens[0].Expired(), true)
+		assert.Equal(t, tokens[0].HasScope(ScopeWrite), false)
+		assert.Equal(t, tokens[1].LastUsed.IsZero(), false)
+
+		// Tokens can only be revoked by their owner.
+		err = m.Delete(tok.ID, 2)
+		assert.Equal(t, err, ErrNoRecord)
+
+		err = m.Delete(tok.ID, 1)
+		assert.NilError(t, err)
+
+		_, err = m.Authenticate(token)
+		assert.Equal(t, err, ErrInvalidCredentials)
+	})
+}
@@ -3,10 +3,8 @@ package models
 import (
 	"database/sql"
 	"errors"
-	"strings"
 	"time"
 
-	"github.com/go-sql-driver/mysql"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,12 +16,14 @@ type UserModelInterface interface {
 
 // Define a new User struct. Notice how the field names and types align
 // with the columns in the database "users" table?
+// Disabled users can't log in or use their API tokens.
 type User struct {
 	ID             int
 	Name           string
 	Email          string
 	HashedPassword []byte
 	Created        time.Time
+	Disabled       bool
 }
 
 // Define a new UserModel struct which wraps a database connection pool.
@@ -40,23 +40,19 @@ func (m *UserModel) Insert(name, email, password string) error {
 	}
 
 	stmt := `INSERT INTO users (name, email, hashed_password, created)
-    VALUES(?, ?, ?, UTC_TIMESTAMP())`
+    VALUES(?, ?, ?, ` + backendOf(m.DB).now() + `)`
 
 	// Use the Exec() method to insert the user details and hashed password
 	// into the users table.
 	_, err = m.DB.Exec(stmt, name, email, string(hashedPassword))
 	if err != nil {
-		// If this returns an error, we use the errors.As() function to check
-		// whether the error has the type *mysql.MySQLError. If it does, the
-		// error will be assigned to the mySQLError variable. We can then check
-		// whether or not the error relates to our users_uc_email key by
-		// checking if the error code equals 1062 and the contents of the error
-		// message string. If it does, we return an ErrDuplicateEmail error.
-		var mySQLError *mysql.MySQLError
-		if errors.As(err, &mySQLError) {
-			if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email") {
-				return ErrDuplicateEmail
-			}
+		// If this returns an error, we use the isDuplicate() helper to check
+		// whether it's a unique key violation, which MySQL and SQLite report
+		// differently. The users_uc_email key is the only unique key on the
+		// table which an insert can violate, so that means the email is
+		// taken. If it is, we return an ErrDuplicateEmail error.
+		if isDuplicate(err) {
+			return ErrDuplicateEmail
 		}
 		return err
 	}
@@ -72,10 +68,11 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 	// no matching email exists we return the ErrInvalidCredentials error.
 	var id int
 	var hashedPassword []byte
+	var disabled bool
 
-	stmt := "SELECT id, hashed_password FROM users WHERE email = ?"
+	stmt := "SELECT id, hashed_password, disabled FROM users WHERE email = ?"
 
-	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
+	err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword, &disabled)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, ErrInvalidCredentials
@@ -93,16 +90,90 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
 		return 0, err
 	}
 
+	// The password is correct, but a disabled user still isn't allowed in. We
+	// only check this after the password so that the error doesn't reveal
+	// which accounts are disabled.
+	if disabled {
+		return 0, ErrAccountDisabled
+	}
+
 	// Otherwise, the password is correct. Return the user ID.
 	return id, nil
 }
 
 // We'll use the Exists method to check if a user exists with a specific ID.
+// Disabled users are treated as if they don't exist, which logs them out.
 func (m *UserModel) Exists(id int) (bool, error) {
 	var exists bool
 
-	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ?)"
+	stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ? AND NOT disabled)"
 
 	err := m.DB.QueryRow(stmt, id).Scan(&exists)
 	return exists, err
 }
+
+// GetByEmail 返回指定邮箱地址的用户，不存在时返回 ErrNoRecord
+func (m *UserModel) GetByEmail(email string) (User, error) {
+	var u User
+
+	stmt := "SELECT id, name, email, hashed_password, created, disabled FROM users WHERE email = ?"
+
+	err := m.DB.QueryRow(stmt, email).Scan(&u.ID, &u.Name, &u.Email, &u.HashedPassword, &u.Created, &u.Disabled)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return User{}, ErrNoRecord
+		}
+		return User{}, err
+	}
+
+	return u, nil
+}
+
+// SetPassword 把用户的密码修改为 password，用户不存在时返回 ErrNoRecord
+func (m *UserModel) SetPassword(id int, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err != nil {
+		return err
+	}
+
+	result, err := m.DB.Exec("UPDATE users SET hashed_password = ? WHERE id = ?", string(hashedPassword), id)
+	if err != nil {
+		return err
+	}
+
+	return m.checkUpdated(result, id)
+}
+
+// SetDisabled 禁用或重新启用用户，用户不存在时返回 ErrNoRecord
+func (m *UserModel) SetDisabled(id int, disabled bool) error {
+	result, err := m.DB.Exec("UPDATE users SET disabled = ? WHERE id = ?", disabled, id)
+	if err != nil {
+		return err
+	}
+
+	return m.checkUpdated(result, id)
+}
+
+// checkUpdated returns ErrNoRecord if an UPDATE of the user with the given ID
+// didn't find them. MySQL only counts the rows which actually changed, so when
+// nothing changed we have to check whether the user exists.
+func (m *UserModel) checkUpdated(result sql.Result, id int) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	var exists bool
+	err = m.DB.QueryRow("SELECT EXISTS(SELECT true FROM users WHERE id = ?)", id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNoRecord
+	}
+
+	return nil
+}
@@ -7,51 +7,121 @@ import (
 )
 
 func TestUserModelExists(t *testing.T) {
-	// Skip the test if the "-short" flag is provided when running the test.
-	if testing.Short() {
-		t.Skip("models: skipping integration test")
-	}
-
-	// Set up a suite of table-driven tests and expected results.
-	tests := []struct {
-		name   string
-		userID int
-		want   bool
-	}{
-		{
-			name:   "Valid ID",
-			userID: 1,
-			want:   true,
-		},
-		{
-			name:   "Zero ID",
-			userID: 0,
-			want:   false,
-		},
-		{
-			name:   "Non-existent ID",
-			userID: 2,
-			want:   false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Call the newTestDB() helper function to get a connection pool to
-			// our test database. Calling this here -- inside t.Run() -- means
-			// that fresh database tables and data will be set up and torn down
-			// for each sub-test.
-			db := newTestDB(t)
-
-			// Create a new instance of the UserModel.
-			m := UserModel{db}
-
-			// Call the UserModel.Exists() method and check that the return
-			// value and error match the expected values for the sub-test.
-			exists, err := m.Exists(tt.userID)
-
-			assert.Equal(t, exists, tt.want)
-			assert.NilError(t, err)
-		})
-	}
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		// Set up a suite of table-driven tests and expected results.
+		tests := []struct {
+			name   string
+			userID int
+			want   bool
+		}{
+			{
+				name:   "Valid ID",
+				userID: 1,
+				want:   true,
+			},
+			{
+				name:   "Zero ID",
+				userID: 0,
+				want:   false,
+			},
+			{
+				name:   "Non-existent ID",
+				userID: 2,
+				want:   false,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				// Call the newTestDB() helper function to get a connection pool to
+				// our test database. Calling this here -- inside t.Run() -- means
+				// that fresh database tables and data will be set up and torn down
+				// for each sub-test.
+				db := newTestDB(t, backend)
+
+				// Create a new instance of the UserModel.
+				m := UserModel{db}
+
+				// Call the UserModel.Exists() method and check that the return
+				// value and error match the expected values for the sub-test.
+				exists, err := m.Exists(tt.userID)
+
+				assert.Equal(t, exists, tt.want)
+				assert.NilError(t, err)
+			})
+		}
+	})
+}
+
+func TestUserModelGetByEmail(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+		m := UserModel{db}
+
+		user, err := m.GetByEmail("alice@example.com")
+		assert.NilError(t, err)
+		assert.Equal(t, user.ID, 1)
+		assert.Equal(t, user.Name, "Alice Jones")
+		assert.Equal(t, user.Disabled, false)
+
+		_, err = m.GetByEmail("nobody@example.com")
+		assert.Equal(t, err, ErrNoRecord)
+	})
+}
+
+func TestUserModelSetPassword(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+		m := UserModel{db}
+
+		err := m.SetPassword(1, "n3w pa$$word")
+		assert.NilError(t, err)
+
+		id, err := m.Authenticate("alice@example.com", "n3w pa$$word")
+		assert.NilError(t, err)
+		assert.Equal(t, id, 1)
+
+		err = m.SetPassword(2, "n3w pa$$word")
+		assert.Equal(t, err, ErrNoRecord)
+	})
+}
+
+func TestUserModelSetDisabled(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, backend Backend) {
+		db := newTestDB(t, backend)
+		m := UserModel{db}
+
+		// Give Alice a known password, so that we can try logging in.
+		err := m.SetPassword(1, "pa$$word")
+		assert.NilError(t, err)
+
+		err = m.SetDisabled(1, true)
+		assert.NilError(t, err)
+
+		_, err = m.Authenticate("alice@example.com", "pa$$word")
+		assert.Equal(t, err, ErrAccountDisabled)
+
+		// A wrong password is still reported as invalid credentials, so that the
+		// error doesn't reveal that the account is disabled.
+		_, err = m.Authenticate("alice@example.com", "wrong")
+		assert.Equal(t, err, ErrInvalidCredentials)
+
+		exists, err := m.Exists(1)
+		assert.NilError(t, err)
+		assert.Equal(t, exists, false)
+
+		// Disabling a disabled user again isn't an error.
+		err = m.SetDisabled(1, true)
+		assert.NilError(t, err)
+
+		err = m.SetDisabled(1, false)
+		assert.NilError(t, err)
+
+		id, err := m.Authenticate("alice@example.com", "pa$$word")
+		assert.NilError(t, err)
+		assert.Equal(t, id, 1)
+
+		err = m.SetDisabled(2, true)
+		assert.Equal(t, err, ErrNoRecord)
+	})
 }
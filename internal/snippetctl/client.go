@@ -0,0 +1,195 @@
+package snippetctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Snippet 是 API 返回的代码片段，字段与服务器的 JSON 表示一一对应
+type Snippet struct {
+	Slug             string     `json:"slug"`
+	Title            string     `json:"title"`
+	Content          string     `json:"content"`
+	Created          time.Time  `json:"created"`
+	Updated          time.Time  `json:"updated"`
+	Expires          *time.Time `json:"expires"`
+	Author           string     `json:"author"`
+	Tags             []string   `json:"tags"`
+	Language         string     `json:"language"`
+	Visibility       string     `json:"visibility"`
+	BurnAfterReading bool       `json:"burn_after_reading"`
+	Protected        bool       `json:"protected"`
+	URL              string     `json:"url"`
+}
+
+// SnippetList 是列表和搜索接口返回的一页代码片段，游标为 0 表示没有更多数据
+type SnippetList struct {
+	Snippets   []Snippet `json:"snippets"`
+	NextCursor int       `json:"next_cursor"`
+	PrevCursor int       `json:"prev_cursor"`
+}
+
+// NewSnippet 保存创建代码片段时发送的数据，Expires 为 nil 表示永不过期
+type NewSnippet struct {
+	Title            string     `json:"title"`
+	Content          string     `json:"content"`
+	Expires          *time.Time `json:"expires,omitempty"`
+	Tags             []string   `json:"tags,omitempty"`
+	Language         string     `json:"language"`
+	Visibility       string     `json:"visibility"`
+	BurnAfterReading bool       `json:"burn_after_reading,omitempty"`
+	Password         string     `json:"password,omitempty"`
+}
+
+// APIError 是服务器返回的 JSON 错误，Fields 保存每个无效字段的错误信息
+type APIError struct {
+	Status  int
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields"`
+}
+
+func (e *APIError) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("%s (%d)", e.Message, e.Status)
+	}
+
+	// List the field errors in a stable order.
+	var fields []string
+	for field, message := range e.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", field, message))
+	}
+	slices.Sort(fields)
+
+	return fmt.Sprintf("%s (%d): %s", e.Message, e.Status, strings.Join(fields, "; "))
+}
+
+// Client 是 snippetbox JSON API 的客户端
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// List 返回一页公开的代码片段，tag 为空表示不按标签过滤，before 为 0 表示第一页
+func (c *Client) List(tag string, size, before int) (SnippetList, error) {
+	q := url.Values{}
+	if tag != "" {
+		q.Set("tag", tag)
+	}
+	if size > 0 {
+		q.Set("size", fmt.Sprint(size))
+	}
+	if before > 0 {
+		q.Set("before", fmt.Sprint(before))
+	}
+
+	var list SnippetList
+	err := c.do(http.MethodGet, "/api/v1/snippets?"+q.Encode(), nil, &list)
+	return list, err
+}
+
+// Search 全文搜索公开的代码片段，page 为 0 表示第一页
+func (c *Client) Search(query string, page int) (SnippetList, error) {
+	q := url.Values{"q": {query}}
+	if page > 0 {
+		q.Set("page", fmt.Sprint(page))
+	}
+
+	var list SnippetList
+	err := c.do(http.MethodGet, "/api/v1/search?"+q.Encode(), nil, &list)
+	return list, err
+}
+
+// Get 返回 slug 对应的代码片段
+func (c *Client) Get(slug string) (Snippet, error) {
+	var res struct {
+		Snippet Snippet `json:"snippet"`
+	}
+	err := c.do(http.MethodGet, "/api/v1/snippets/"+url.PathEscape(slug), nil, &res)
+	return res.Snippet, err
+}
+
+// Create 创建新的代码片段并返回它
+func (c *Client) Create(snippet NewSnippet) (Snippet, error) {
+	var res struct {
+		Snippet Snippet `json:"snippet"`
+	}
+	err := c.do(http.MethodPost, "/api/v1/snippets", snippet, &res)
+	return res.Snippet, err
+}
+
+// Delete 删除 slug 对应的代码片段
+func (c *Client) Delete(slug string) error {
+	return c.do(http.MethodDelete, "/api/v1/snippets/"+url.PathEscape(slug), nil, nil)
+}
+
+// SnippetURL returns the address of a snippet's page on the server.
+func (c *Client) SnippetURL(s Snippet) string {
+	return strings.TrimSuffix(c.BaseURL, "/") + s.URL
+}
+
+// do sends a request to the API with body encoded as JSON, if it isn't nil,
+// and decodes the JSON response into dst, if it isn't nil. An error response
+// is returned as an *APIError.
+func (c *Client) do(method, path string, body any, dst any) error {
+	var r io.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(js)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.BaseURL, "/")+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 400 {
+		apiErr := &APIError{Status: res.StatusCode}
+
+		var envelope struct {
+			Error *APIError `json:"error"`
+		}
+		envelope.Error = apiErr
+
+		// A response which isn't a JSON error, like one from a proxy in front
+		// of the server, is reported by its status alone.
+		if json.NewDecoder(res.Body).Decode(&envelope) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.ToLower(http.StatusText(res.StatusCode))
+		}
+		return apiErr
+	}
+
+	if dst == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(dst)
+}
@@ -0,0 +1,53 @@
+package snippetctl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config 是 snippetctl 的配置文件内容。URL 是服务器地址，Token 是在
+// /user/tokens 页面创建的 API 令牌，为空时只能读取公开的代码片段。
+// InsecureSkipVerify 跳过 TLS 证书检查，只应该在开发时配合自签名证书使用。
+type Config struct {
+	URL                string `json:"url"`
+	Token              string `json:"token"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+}
+
+// DefaultConfigPath returns the default location of the config file, which
+// is snippetctl/config.json in the user's config directory (like
+// ~/.config/snippetctl/config.json on Linux).
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "snippetctl.json"
+	}
+	return filepath.Join(dir, "snippetctl", "config.json")
+}
+
+// LoadConfig reads and checks the JSON config file at path.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Config{}, fmt.Errorf("config file %s doesn't exist, create it with the server's \"url\" and your API \"token\"", path)
+		}
+		return Config{}, err
+	}
+
+	err = json.Unmarshal(b, &cfg)
+	if err != nil {
+		return Config{}, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	if cfg.URL == "" {
+		return Config{}, fmt.Errorf("config file %s: \"url\" must be set", path)
+	}
+
+	return cfg, nil
+}
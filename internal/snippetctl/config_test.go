@@ -0,0 +1,54 @@
+package snippetctl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"snippetbox.xmxxmx.us/internal/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantURL string
+		wantErr bool
+	}{
+		{
+			name:    "Valid",
+			content: `{"url": "https://snippetbox.example.com", "token": "sbx_abc"}`,
+			wantURL: "https://snippetbox.example.com",
+		},
+		{
+			name:    "Missing URL",
+			content: `{"token": "sbx_abc"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid JSON",
+			content: `url = "https://snippetbox.example.com"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			err := os.WriteFile(path, []byte(tt.content), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadConfig(path)
+
+			assert.Equal(t, err != nil, tt.wantErr)
+			assert.Equal(t, cfg.URL, tt.wantURL)
+		})
+	}
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := LoadConfig(filepath.Join(t.TempDir(), "config.json"))
+		assert.StringContains(t, err.Error(), "doesn't exist")
+	})
+}
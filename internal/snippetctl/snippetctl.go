@@ -0,0 +1,360 @@
+// Package snippetctl 实现 snippetctl 命令行客户端，它通过 JSON API 创建、读取、
+// 列出、搜索和删除 snippetbox 服务器上的代码片段。
+package snippetctl
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"snippetbox.xmxxmx.us/internal/cmdline"
+)
+
+// Env 保存命令运行时使用的输入输出。HTTPClient 为 nil 时根据配置文件创建，
+// 测试时可以替换为连接 httptest 服务器的客户端。
+type Env struct {
+	Stdin      io.Reader
+	Stdout     io.Writer
+	Stderr     io.Writer
+	HTTPClient *http.Client
+}
+
+const usage = `Usage: snippetctl [-config file] [-json] <command> [arguments]
+
+Commands:
+  create -t title [-lang language] [-tags a,b] [-visibility v] [-expires d]
+                   create a snippet from standard input and print its URL
+  get <slug>       print a snippet
+  raw <slug>       print just the content of a snippet
+  list [-tag t] [-size n] [-before cursor]
+                   list public snippets, newest first
+  search [-page n] <terms>
+                   search public snippets
+  delete <slug>    delete one of your snippets
+
+Flags:
+`
+
+// command is one of snippetctl's subcommands. It returns an error which is
+// printed to stderr.
+type command func(c *cli, args []string) error
+
+var commands = map[string]command{
+	"create": (*cli).create,
+	"get":    (*cli).get,
+	"raw":    (*cli).raw,
+	"list":   (*cli).list,
+	"search": (*cli).search,
+	"delete": (*cli).delete,
+}
+
+// cli holds the state shared by the subcommands.
+type cli struct {
+	env    Env
+	client *Client
+	json   bool
+}
+
+// Run runs snippetctl with the given command line arguments (not including
+// the program name) and returns the exit status.
+func Run(args []string, env Env) int {
+	fs := flag.NewFlagSet("snippetctl", flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	fs.Usage = func() {
+		fmt.Fprint(env.Stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	configPath := fs.String("config", DefaultConfigPath(), "Path of the config file")
+	jsonOutput := fs.Bool("json", false, "Print the API's JSON instead of text")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(env.Stderr, "snippetctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "snippetctl: %v\n", err)
+		return 1
+	}
+
+	httpClient := env.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify},
+			},
+		}
+	}
+
+	c := &cli{
+		env:    env,
+		client: &Client{BaseURL: cfg.URL, Token: cfg.Token, HTTPClient: httpClient},
+		json:   *jsonOutput,
+	}
+
+	err = cmd(c, fs.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "snippetctl %s: %v\n", fs.Arg(0), err)
+	}
+
+	return cmdline.ExitStatus(err)
+}
+
+// slugArg parses the arguments of a subcommand which takes a single slug.
+func (c *cli) slugArg(name string, args []string) (string, error) {
+	fs := cmdline.FlagSet("snippetctl "+name, c.env.Stderr)
+	err := cmdline.Parse(fs, args, 1)
+	if err != nil {
+		return "", err
+	}
+	if fs.NArg() == 0 {
+		return "", cmdline.UsageError("missing snippet slug")
+	}
+	return fs.Arg(0), nil
+}
+
+// printJSON writes v to stdout as indented JSON.
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.env.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printList writes a page of snippets to stdout, one per line. If there's
+// another page, the command which lists it is printed to stderr, using next
+// to build it from the cursor.
+func (c *cli) printList(list SnippetList, next func(cursor int) string) error {
+	if c.json {
+		return c.printJSON(list)
+	}
+
+	tw := tabwriter.NewWriter(c.env.Stdout, 0, 8, 2, ' ', 0)
+	for _, s := range list.Snippets {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Slug, s.Title, c.client.SnippetURL(s))
+	}
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	if list.NextCursor != 0 {
+		fmt.Fprintf(c.env.Stderr, "More snippets: %s\n", next(list.NextCursor))
+	}
+
+	return nil
+}
+
+// create 从标准输入读取内容并创建代码片段，输出新片段的地址
+func (c *cli) create(args []string) error {
+	fs := cmdline.FlagSet("snippetctl create", c.env.Stderr)
+	title := fs.String("t", "", "Title of the snippet (required)")
+	language := fs.String("lang", "auto", "Language of the snippet, or auto to detect it")
+	tags := fs.String("tags", "", "Comma separated list of tags")
+	visibility := fs.String("visibility", "public", "Visibility: public, unlisted or private")
+	expires := fs.String("expires", "8760h", `How long until the snippet expires, like 24h, or "never"`)
+	burn := fs.Bool("burn", false, "Destroy the snippet the first time someone else views it")
+
+	err := cmdline.Parse(fs, args, 0)
+	if err != nil {
+		return err
+	}
+	if *title == "" {
+		return cmdline.UsageError("the -t flag is required")
+	}
+
+	snippet := NewSnippet{
+		Title:            *title,
+		Language:         *language,
+		Visibility:       *visibility,
+		BurnAfterReading: *burn,
+	}
+
+	if *expires != "never" {
+		d, err := time.ParseDuration(*expires)
+		if err != nil || d <= 0 {
+			return cmdline.UsageError(fmt.Sprintf("invalid -expires value %q", *expires))
+		}
+		t := time.Now().Add(d).UTC()
+		snippet.Expires = &t
+	}
+
+	for _, tag := range strings.Split(*tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			snippet.Tags = append(snippet.Tags, tag)
+		}
+	}
+
+	content, err := io.ReadAll(c.env.Stdin)
+	if err != nil {
+		return err
+	}
+	snippet.Content = string(content)
+
+	s, err := c.client.Create(snippet)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(s)
+	}
+
+	_, err = fmt.Fprintln(c.env.Stdout, c.client.SnippetURL(s))
+	return err
+}
+
+// get 输出代码片段的标题、作者等信息和内容
+func (c *cli) get(args []string) error {
+	slug, err := c.slugArg("get", args)
+	if err != nil {
+		return err
+	}
+
+	s, err := c.client.Get(slug)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(s)
+	}
+
+	expires := "never"
+	if s.Expires != nil {
+		expires = s.Expires.UTC().Format(time.RFC3339)
+	}
+
+	fmt.Fprintf(c.env.Stdout, "Title:      %s\n", s.Title)
+	fmt.Fprintf(c.env.Stdout, "Author:     %s\n", s.Author)
+	fmt.Fprintf(c.env.Stdout, "Created:    %s\n", s.Created.UTC().Format(time.RFC3339))
+	fmt.Fprintf(c.env.Stdout, "Expires:    %s\n", expires)
+	fmt.Fprintf(c.env.Stdout, "Visibility: %s\n", s.Visibility)
+	if len(s.Tags) > 0 {
+		fmt.Fprintf(c.env.Stdout, "Tags:       %s\n", strings.Join(s.Tags, ", "))
+	}
+	fmt.Fprintf(c.env.Stdout, "URL:        %s\n\n", c.client.SnippetURL(s))
+
+	return c.writeContent(s.Content)
+}
+
+// raw 只输出代码片段的内容，方便在脚本中使用
+func (c *cli) raw(args []string) error {
+	slug, err := c.slugArg("raw", args)
+	if err != nil {
+		return err
+	}
+
+	s, err := c.client.Get(slug)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(c.env.Stdout, s.Content)
+	return err
+}
+
+// writeContent writes a snippet's content to stdout, ending it with a newline
+// if it doesn't already have one so that the shell prompt starts on its own
+// line.
+func (c *cli) writeContent(content string) error {
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	_, err := io.WriteString(c.env.Stdout, content)
+	return err
+}
+
+// list 列出公开的代码片段
+func (c *cli) list(args []string) error {
+	fs := cmdline.FlagSet("snippetctl list", c.env.Stderr)
+	tag := fs.String("tag", "", "Only list snippets with this tag")
+	size := fs.Int("size", 0, "Number of snippets to list")
+	before := fs.Int("before", 0, "Cursor of the page to list, from a previous list")
+
+	err := cmdline.Parse(fs, args, 0)
+	if err != nil {
+		return err
+	}
+
+	list, err := c.client.List(*tag, *size, *before)
+	if err != nil {
+		return err
+	}
+
+	return c.printList(list, func(cursor int) string {
+		cmd := fmt.Sprintf("snippetctl list -before %d", cursor)
+		if *tag != "" {
+			cmd += " -tag " + *tag
+		}
+		return cmd
+	})
+}
+
+// search 全文搜索公开的代码片段
+func (c *cli) search(args []string) error {
+	fs := cmdline.FlagSet("snippetctl search", c.env.Stderr)
+	page := fs.Int("page", 0, "Page of results to show, from a previous search")
+
+	// The search terms can be given as several arguments, so there's no
+	// limit on the number left after the flags.
+	err := fs.Parse(args)
+	if err != nil {
+		return cmdline.UsageError(err.Error())
+	}
+
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return cmdline.UsageError("missing search terms")
+	}
+
+	list, err := c.client.Search(query, *page)
+	if err != nil {
+		return err
+	}
+
+	return c.printList(list, func(cursor int) string {
+		return fmt.Sprintf("snippetctl search -page %d %s", cursor, query)
+	})
+}
+
+// delete 删除当前用户的一个代码片段
+func (c *cli) delete(args []string) error {
+	slug, err := c.slugArg("delete", args)
+	if err != nil {
+		return err
+	}
+
+	err = c.client.Delete(slug)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return c.printJSON(map[string]string{"deleted": slug})
+	}
+
+	_, err = fmt.Fprintf(c.env.Stdout, "Deleted %s\n", slug)
+	return err
+}
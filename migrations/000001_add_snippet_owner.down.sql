@@ -0,0 +1,6 @@
+ALTER TABLE snippets DROP FOREIGN KEY fk_snippets_user_id;
+
+ALTER TABLE snippets DROP COLUMN user_id;
+
+-- The placeholder user created by the up migration owns nothing any more.
+DELETE FROM users WHERE email = 'former-owner@snippetbox.invalid';
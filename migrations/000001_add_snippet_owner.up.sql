@@ -0,0 +1,22 @@
+-- Record which user created each snippet. Existing rows pre-date ownership, so
+-- before the column is made mandatory they're given to a placeholder user,
+-- rather than to a real account which would then be shown as their author and
+-- be able to edit and delete them.
+ALTER TABLE snippets ADD COLUMN user_id INTEGER NULL;
+
+-- The placeholder is only created if there are snippets to give it. Its
+-- password is the hash of a random password which was thrown away, so nobody
+-- can log in as it.
+INSERT INTO users (name, email, hashed_password, created)
+SELECT 'Former owner', 'former-owner@snippetbox.invalid',
+    '$2a$12$WwFVZVtVAT4WT/wdGqWKyuBA4bDrxZ73mDP04DIL42NtY72sOKPDy', UTC_TIMESTAMP()
+FROM snippets LIMIT 1;
+
+UPDATE snippets SET user_id = (
+    SELECT id FROM users WHERE email = 'former-owner@snippetbox.invalid'
+) WHERE user_id IS NULL;
+
+ALTER TABLE snippets MODIFY user_id INTEGER NOT NULL;
+
+ALTER TABLE snippets ADD CONSTRAINT fk_snippets_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id);
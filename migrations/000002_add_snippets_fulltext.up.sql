@@ -0,0 +1,3 @@
+-- Full-text index used by SnippetModel.Search(). The MATCH() column list in
+-- the search query must be identical to the columns indexed here.
+ALTER TABLE snippets ADD FULLTEXT INDEX idx_snippets_fulltext (title, content);
@@ -0,0 +1,3 @@
+-- The language used to syntax highlight a snippet. An empty string means
+-- plain text, which is what all existing snippets are.
+ALTER TABLE snippets ADD COLUMN language VARCHAR(30) NOT NULL DEFAULT '';
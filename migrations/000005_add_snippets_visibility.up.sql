@@ -0,0 +1,4 @@
+-- Who can see a snippet: public snippets are listed, unlisted snippets are
+-- only reachable by link and private snippets are only visible to their
+-- owner. Existing snippets stay public.
+ALTER TABLE snippets ADD COLUMN visibility VARCHAR(10) NOT NULL DEFAULT 'public';
@@ -0,0 +1,7 @@
+-- Random, URL-safe identifiers used in public snippet links so that snippet
+-- URLs can't be enumerated. Existing snippets are given a slug from 8 random
+-- bytes, encoded the same way as the application does (base64url, no padding).
+ALTER TABLE snippets ADD COLUMN slug CHAR(11) NULL AFTER id;
+UPDATE snippets SET slug = SUBSTRING(REPLACE(REPLACE(TO_BASE64(RANDOM_BYTES(8)), '+', '-'), '/', '_'), 1, 11);
+ALTER TABLE snippets MODIFY slug CHAR(11) NOT NULL;
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_slug UNIQUE (slug);
@@ -0,0 +1,6 @@
+-- Burn after reading snippets are destroyed the first time someone other
+-- than their owner views them. A destroyed snippet is kept as a tombstone
+-- (burned = TRUE, content cleared) so that later visitors can be told what
+-- happened to it.
+ALTER TABLE snippets ADD COLUMN burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE snippets ADD COLUMN burned BOOLEAN NOT NULL DEFAULT FALSE;
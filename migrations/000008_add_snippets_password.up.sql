@@ -0,0 +1,3 @@
+-- Optional access password for a snippet, stored as a bcrypt hash like
+-- users.hashed_password. NULL means the snippet isn't password protected.
+ALTER TABLE snippets ADD COLUMN hashed_password CHAR(60) NULL;
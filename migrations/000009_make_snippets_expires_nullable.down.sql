@@ -0,0 +1,4 @@
+-- Snippets which never expire are given an expiry far in the future, since
+-- the column can't hold NULL any more.
+UPDATE snippets SET expires = '9999-12-31 23:59:59' WHERE expires IS NULL;
+ALTER TABLE snippets MODIFY expires DATETIME NOT NULL;
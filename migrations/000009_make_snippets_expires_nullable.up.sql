@@ -0,0 +1,2 @@
+-- A NULL expires means that the snippet never expires.
+ALTER TABLE snippets MODIFY expires DATETIME NULL;
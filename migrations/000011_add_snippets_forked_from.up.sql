@@ -0,0 +1,4 @@
+-- The snippet that a snippet was forked (copied) from, if any. Forks outlive
+-- the snippet they were forked from, so deleting it just clears the link.
+ALTER TABLE snippets ADD COLUMN forked_from INTEGER NULL;
+ALTER TABLE snippets ADD CONSTRAINT fk_snippets_forked_from FOREIGN KEY (forked_from) REFERENCES snippets(id) ON DELETE SET NULL;
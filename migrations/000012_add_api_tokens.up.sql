@@ -0,0 +1,16 @@
+-- Personal API tokens. Only the SHA-256 hash of each token is stored, the
+-- token itself is shown to the user once when it's created. scopes is a
+-- comma separated list of "read" and "write". A NULL last_used means the
+-- token has never been used, and a NULL expires means it never expires.
+CREATE TABLE api_tokens (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    scopes VARCHAR(20) NOT NULL,
+    created DATETIME NOT NULL,
+    last_used DATETIME NULL,
+    expires DATETIME NULL,
+    CONSTRAINT api_tokens_uc_token_hash UNIQUE (token_hash),
+    CONSTRAINT fk_api_tokens_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- Disabled users can't log in, and their sessions and API tokens stop
+-- working, but their snippets are kept.
+ALTER TABLE users ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;